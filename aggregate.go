@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type (
+	// AggregateResult represents the result for an aggregate operation, which
+	// merges the normalized scores of every provider into a single score
+	AggregateResult struct {
+		// Score is the weighted mean of the normalized scores, from 0 to 100
+		Score      float32              `json:"score"`
+		Components []AggregateComponent `json:"components"`
+	}
+
+	// AggregateComponent represents the part of a single provider in an
+	// aggregate score. Either Score or Error is defined.
+	AggregateComponent struct {
+		Provider string  `json:"provider"`
+		ID       string  `json:"id"`
+		Score    float32 `json:"score,omitempty"`
+		// Votes is the number of votes or reviews behind the score, zero if
+		// the provider doesn't tell
+		Votes uint `json:"votes,omitempty"`
+		// Weight is the share of the score in the aggregate, all weights add
+		// up to 1
+		Weight float64      `json:"weight"`
+		Error  *ErrorOutput `json:"error,omitempty"`
+	}
+)
+
+// providerScales is the highest score of each provider
+var providerScales = map[string]float32{
+	IMDB:    10,
+	RottenT: 100,
+}
+
+// DefaultAggregateWeights is the weight of each provider in aggregate
+// operations
+var DefaultAggregateWeights = map[string]float64{
+	IMDB:    1,
+	RottenT: 1,
+}
+
+// votePriors is the number of votes behind a score of each provider needed
+// to count half of its weight. A score with far more votes counts almost all
+// of its weight. IMDb ratings have thousands of votes while Tomatometers
+// have tens of reviews.
+var votePriors = map[string]float64{
+	IMDB:    1000,
+	RottenT: 20,
+}
+
+// normalizeScore converts a score of the given provider to a 0-100 scale
+// rounded to one decimal, e.g. 79 for an IMDb rating of 7.9
+func normalizeScore(provider string, score float32) float32 {
+	scale, ok := providerScales[provider]
+	if !ok || scale == 0 {
+		return score
+	}
+	normalized := float64(score) * 100 / float64(scale)
+	return float32(math.Round(normalized*10) / 10)
+}
+
+// Aggregate retrieves the score of every given provider ID and merges them
+// into a single score. Each provider counts as much as its weight, reduced
+// when the score is backed by few votes. Providers missing from weights use
+// DefaultAggregateWeights.
+func Aggregate(ctx context.Context, ids map[string]string, weights map[string]float64, fetcher Fetcher) (*AggregateResult, error) {
+	scores := ScoreAll(ctx, ids, fetcher, false)
+
+	result := &AggregateResult{
+		Components: make([]AggregateComponent, 0, len(scores.Results)),
+	}
+
+	var total, sum float64
+	for _, r := range scores.Results {
+		component := AggregateComponent{
+			Provider: r.Provider,
+			ID:       r.ID,
+			Error:    r.Error,
+		}
+
+		if r.Result != nil {
+			component.Score = r.Result.NormalizedScore
+			component.Votes = votesOf(r.Result)
+			component.Weight = aggregateWeight(r.Provider, weights) * voteConfidence(r.Provider, component.Score, component.Votes)
+			total += component.Weight
+			sum += component.Weight * float64(component.Score)
+		}
+
+		result.Components = append(result.Components, component)
+	}
+
+	if total == 0 {
+		// Failing providers tell why better than not found, e.g. rate limited
+		for _, r := range scores.Results {
+			if r.err != nil {
+				return nil, fmt.Errorf("no provider returned a score: %s: %w", aggregateErrors(result.Components), r.err)
+			}
+		}
+		return nil, &ProviderError{Kind: ErrNotFound, Err: errors.New("no provider with weight returned a score")}
+	}
+
+	for i := range result.Components {
+		result.Components[i].Weight /= total
+	}
+	result.Score = float32(math.Round(sum/total*10) / 10)
+
+	return result, nil
+}
+
+func aggregateWeight(provider string, weights map[string]float64) float64 {
+	if weight, ok := weights[provider]; ok {
+		return weight
+	}
+	return DefaultAggregateWeights[provider]
+}
+
+// votesOf returns the number of votes behind the score of the given result.
+// Tomatometers are backed by critic reviews.
+func votesOf(result *ScoreResult) uint {
+	if result.Provider == RottenT {
+		return result.CriticCount
+	}
+	return result.Votes
+}
+
+// voteConfidence returns how much of its weight a score backed by the given
+// number of votes counts. A score of zero without votes is a title nobody
+// rated yet and doesn't count. Otherwise an unknown number of votes counts
+// fully.
+func voteConfidence(provider string, score float32, votes uint) float64 {
+	if votes == 0 && score == 0 {
+		return 0
+	}
+	prior := votePriors[provider]
+	if votes == 0 || prior == 0 {
+		return 1
+	}
+	return float64(votes) / (float64(votes) + prior)
+}
+
+func aggregateErrors(components []AggregateComponent) string {
+	messages := make([]string, 0, len(components))
+	for _, c := range components {
+		if c.Error != nil {
+			messages = append(messages, c.Provider)
+		}
+	}
+	return "failed " + strings.Join(messages, ", ")
+}
+
+// parseWeights parses a list of weights per provider separated by commas,
+// e.g. imdb=2,rotten=1
+func parseWeights(value string) (map[string]float64, error) {
+	return parsePairs(value, "weight", "provider=weight", supportedProviders, func(text string) (float64, error) {
+		weight, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, err
+		}
+		if math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return 0, errors.New("must be a finite number")
+		}
+		if weight < 0 {
+			return 0, errors.New("can't be negative")
+		}
+		return weight, nil
+	})
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestNormalizeScore(t *testing.T) {
+	cases := []struct {
+		provider string
+		score    float32
+		expected float32
+	}{
+		{IMDB, 7.9, 79},
+		{IMDB, 10, 100},
+		{RottenT, 94, 94},
+		{"unknown", 42, 42},
+	}
+
+	for _, c := range cases {
+		if score := normalizeScore(c.provider, c.score); score != c.expected {
+			t.Errorf("Normalized %s score %v was incorrect, got: %v, expected: %v", c.provider, c.score, score, c.expected)
+		}
+	}
+}
+
+func TestScoreNormalized(t *testing.T) {
+	imdb, err := NewIMDb(testFetcher).Score(context.Background(), "tt0371746")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotten, err := NewRottenTomatoes(testFetcher).Score(context.Background(), "/m/iron_man")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if imdb.Score != 7.9 || imdb.NormalizedScore != 79 {
+		t.Errorf("IMDb scores were incorrect, got: %v and %v, expected: 7.9 and 79", imdb.Score, imdb.NormalizedScore)
+	}
+	if rotten.Score != 94 || rotten.NormalizedScore != 94 {
+		t.Errorf("RottenTomatoes scores were incorrect, got: %v and %v, expected: 94 and 94", rotten.Score, rotten.NormalizedScore)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	ids := map[string]string{
+		IMDB:    "tt0371746",
+		RottenT: "/m/iron_man",
+	}
+
+	result, err := Aggregate(context.Background(), ids, nil, testFetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 281 reviews count a bit less than 881,325 votes
+	if result.Score != 86.2 {
+		t.Errorf("Score was incorrect, got: %v, expected: 86.2", result.Score)
+	}
+	if len(result.Components) != 2 || result.Components[0].Weight <= result.Components[1].Weight {
+		t.Errorf("Components were incorrect, got: %+v", result.Components)
+	}
+
+	result, err = Aggregate(context.Background(), ids, map[string]float64{RottenT: 0}, testFetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score != 79 || result.Components[0].Weight != 1 || result.Components[1].Weight != 0 {
+		t.Errorf("Result without rotten was incorrect, got: %+v", result)
+	}
+}
+
+func TestAggregatePartialFailure(t *testing.T) {
+	ids := map[string]string{
+		IMDB:    "tt0000000",
+		RottenT: "/m/iron_man",
+	}
+
+	result, err := Aggregate(context.Background(), ids, nil, testFetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score != 94 || result.Components[0].Error == nil {
+		t.Errorf("Result was incorrect, got: %+v", result)
+	}
+
+	_, err = Aggregate(context.Background(), map[string]string{IMDB: "tt0000000"}, nil, testFetcher)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Error was incorrect, got: %v, expected: %v", err, ErrNotFound)
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	weights, err := parseWeights("imdb=2, rotten=0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weights[IMDB] != 2 || weights[RottenT] != 0.5 {
+		t.Errorf("Weights were incorrect, got: %v", weights)
+	}
+
+	for _, value := range []string{"imdb", "netflix=1", "imdb=a", "imdb=-1", "imdb=NaN", "rotten=Inf"} {
+		if _, err := parseWeights(value); err == nil {
+			t.Errorf("Error was expected for '%s'", value)
+		}
+	}
+}
+
+func TestVoteConfidence(t *testing.T) {
+	cases := []struct {
+		provider string
+		score    float32
+		votes    uint
+		expected float64
+	}{
+		// Nobody rated the title yet
+		{IMDB, 0, 0, 0},
+		{RottenT, 0, 0, 0},
+		// The provider doesn't tell how many votes
+		{RottenT, 94, 0, 1},
+		{RottenT, 0, 20, 0.5},
+		{IMDB, 79, 1000, 0.5},
+	}
+
+	for _, c := range cases {
+		if confidence := voteConfidence(c.provider, c.score, c.votes); confidence != c.expected {
+			t.Errorf("Confidence of %s score %v with %d votes was incorrect, got: %v, expected: %v", c.provider, c.score, c.votes, confidence, c.expected)
+		}
+	}
+}
+
+func TestAggregateFailureKind(t *testing.T) {
+	fetcher := failingFetcher{statusError("https://www.imdb.com/title/tt0371746", http.StatusTooManyRequests)}
+
+	_, err := Aggregate(context.Background(), map[string]string{IMDB: "tt0371746", RottenT: "/m/iron_man"}, nil, fetcher)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Error was incorrect, got: %v, expected: %v", err, ErrRateLimited)
+	}
+	if code := exitCode(err); code != ExitRateLimited {
+		t.Errorf("Exit code was incorrect, got: %d, expected: %d", code, ExitRateLimited)
+	}
+}
+
+type failingFetcher struct {
+	err error
+}
+
+func (f failingFetcher) Get(ctx context.Context, url string) (*Response, error) {
+	return nil, f.err
+}
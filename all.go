@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// AllProviders represents every supported provider in a single operation
+const AllProviders = "all"
+
+type (
+	// MultiScoreResult represents the merged result for a score operation
+	// using more than one provider
+	MultiScoreResult struct {
+		Results []ProviderScoreResult `json:"results"`
+	}
+
+	// ProviderScoreResult represents the outcome of a score operation for a
+	// single provider. Either Result or Error is defined.
+	ProviderScoreResult struct {
+		Provider string       `json:"provider"`
+		ID       string       `json:"id"`
+		Result   *ScoreResult `json:"result,omitempty"`
+		Error    *ErrorOutput `json:"error,omitempty"`
+
+		// err is the error behind Error, so its kind isn't lost
+		err error
+	}
+)
+
+// ScoreAll retrieves the score of every given provider ID at the same time.
+// A failing provider is reported in its own entry and doesn't affect the
+// others. Setting ratings also fetches the rating distribution of providers
+// that have one.
+func ScoreAll(ctx context.Context, ids map[string]string, fetcher Fetcher, ratings bool) *MultiScoreResult {
+	result := &MultiScoreResult{
+		Results: make([]ProviderScoreResult, 0, len(ids)),
+	}
+
+	// Keep the same order of supported providers so output is stable
+	for _, provider := range supportedProviders {
+		id, ok := ids[provider]
+		if !ok {
+			continue
+		}
+		result.Results = append(result.Results, ProviderScoreResult{
+			Provider: provider,
+			ID:       id,
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := range result.Results {
+		wg.Add(1)
+		go func(r *ProviderScoreResult) {
+			defer wg.Done()
+
+			result, err := score(ctx, withRatings(newProvider(r.Provider, fetcher), ratings), r.ID)
+			if err != nil {
+				r.err = err
+				r.Error = newErrorOutput(err)
+			} else {
+				r.Result = result
+			}
+		}(&result.Results[i])
+	}
+	wg.Wait()
+
+	return result
+}
+
+// parseProviderIDs parses a list of IDs in the format provider=id separated
+// by commas, e.g. imdb=tt0371746,rotten=/m/iron_man
+func parseProviderIDs(value string) (map[string]string, error) {
+	result, err := parsePairs(value, "id", "provider=id", supportedProviders, func(id string) (string, error) {
+		return id, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("at least one provider id must be defined")
+	}
+
+	return result, nil
+}
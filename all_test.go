@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScoreAll(t *testing.T) {
+	ids := map[string]string{
+		IMDB:    "tt0371746",
+		RottenT: "/m/movie_without_fixture",
+	}
+
+	result := ScoreAll(context.Background(), ids, testFetcher, false)
+	if len(result.Results) != 2 {
+		t.Fatalf("Size was incorrect, got: %d, expected: 2", len(result.Results))
+	}
+
+	imdb := result.Results[0]
+	if imdb.Provider != IMDB || imdb.Result == nil || imdb.Error != nil {
+		t.Errorf("IMDb result was invalid, got: %+v", imdb)
+	}
+
+	rotten := result.Results[1]
+	if rotten.Provider != RottenT || rotten.Result != nil || rotten.Error == nil {
+		t.Fatalf("RottenTomatoes result should have failed, got: %+v", rotten)
+	}
+
+	if rotten.Error.Kind != "not_found" || rotten.Error.URL == "" {
+		t.Errorf("RottenTomatoes error was incorrect, got: %+v", rotten.Error)
+	}
+}
+
+func TestParseProviderIDs(t *testing.T) {
+	ids, err := parseProviderIDs("imdb=tt0371746, rotten=/m/iron_man")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids[IMDB] != "tt0371746" || ids[RottenT] != "/m/iron_man" {
+		t.Errorf("IDs were incorrect, got: %v", ids)
+	}
+
+	invalid := []string{"", "tt0371746", "netflix=123", "imdb="}
+	for _, value := range invalid {
+		if _, err := parseProviderIDs(value); err == nil {
+			t.Errorf("Error was expected for '%s'", value)
+		}
+	}
+}
+
+func TestScoreAllRatings(t *testing.T) {
+	ids := map[string]string{
+		IMDB:    "tt0371746",
+		RottenT: "/m/iron_man",
+	}
+
+	result := ScoreAll(context.Background(), ids, testFetcher, true)
+	if imdb := result.Results[0].Result; imdb == nil || len(imdb.Distribution) != 10 {
+		t.Errorf("IMDb result was incorrect, got: %+v", result.Results[0])
+	}
+}
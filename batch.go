@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status of each line of a batch operation
+const (
+	BatchStatusOK    = "ok"
+	BatchStatusError = "error"
+)
+
+type (
+	// Batch runs one request per line of an input
+	Batch struct {
+		Fetcher Fetcher
+		// Workers is the maximum number of requests running at the same time
+		Workers int
+		// Timeout limits how long each request may take, zero means no limit
+		Timeout time.Duration
+	}
+
+	// BatchResult represents the outcome of a single line of a batch operation.
+	// Either Result or Error is defined.
+	BatchResult struct {
+		Line int `json:"line"`
+		Request
+		Status string       `json:"status"`
+		Result interface{}  `json:"result,omitempty"`
+		Error  *ErrorOutput `json:"error,omitempty"`
+	}
+
+	batchJob struct {
+		line    int
+		request Request
+		err     error
+	}
+)
+
+// Run reads one request per line from r and runs them with at most the
+// configured number of workers at the same time. Each result is written to w
+// as a JSON line as soon as its request completes, so results may come out of
+// order and carry the line number of their request. When the context is done,
+// no more lines are read and outstanding requests are aborted.
+func (b *Batch) Run(ctx context.Context, r io.Reader, w io.Writer) error {
+	workers := b.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan batchJob)
+	results := make(chan BatchResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results <- b.run(ctx, job)
+			}
+		}()
+	}
+
+	// Only one goroutine writes so lines never interleave
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		encoder := json.NewEncoder(w)
+		for result := range results {
+			if err == nil {
+				err = encoder.Encode(result)
+			}
+		}
+		done <- err
+	}()
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	canceled := false
+	for !canceled && scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		req, err := parseBatchLine(text)
+		select {
+		case jobs <- batchJob{line: line, request: req, err: err}:
+		case <-ctx.Done():
+			canceled = true
+		}
+	}
+	close(jobs)
+
+	wg.Wait()
+	close(results)
+
+	if err := <-done; err != nil {
+		return err
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (b *Batch) run(ctx context.Context, job batchJob) BatchResult {
+	result := BatchResult{
+		Line:    job.line,
+		Request: job.request,
+	}
+
+	err := job.err
+	if err == nil {
+		err = result.Request.validate()
+	}
+
+	var data interface{}
+	if err == nil {
+		ctx, cancel := withTimeout(ctx, b.Timeout)
+		data, err = result.Request.execute(ctx, b.Fetcher)
+		cancel()
+	}
+
+	if err != nil {
+		result.Status = BatchStatusError
+		result.Error = newErrorOutput(err)
+	} else {
+		result.Status = BatchStatusOK
+		result.Result = data
+	}
+
+	return result
+}
+
+// parseBatchLine parses a line of a batch input. It's either a JSON object,
+// e.g. {"provider": "imdb", "op": "score", "id": "tt0371746"}, or provider,
+// operation and query/id separated by spaces, e.g. rotten search iron man.
+func parseBatchLine(line string) (Request, error) {
+	var req Request
+
+	if strings.HasPrefix(line, "{") {
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			return req, fmt.Errorf("invalid JSON line: %s", err.Error())
+		}
+		return req, nil
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return req, fmt.Errorf("line '%s' must be in the format provider operation query|id", line)
+	}
+
+	req.Provider = fields[0]
+	req.Operation = fields[1]
+
+	value := strings.Join(fields[2:], " ")
+	if isArgValid(req.Operation, idOperations) {
+		req.ID = value
+	} else {
+		req.Query = value
+	}
+
+	return req, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	input := strings.Join([]string{
+		`{"provider": "imdb", "op": "score", "id": "tt0371746"}`,
+		``,
+		`# comments are ignored`,
+		`rotten search iron man`,
+		`rotten score /m/movie_without_fixture`,
+		`imdb score`,
+		`imdb details tt0371746`,
+		`rotten franchise iron_man`,
+	}, "\n")
+
+	var out bytes.Buffer
+	batch := &Batch{Fetcher: testFetcher, Workers: 2}
+	if err := batch.Run(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(map[int]BatchResult)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var result BatchResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("Line was not valid JSON: %s", line)
+		}
+		results[result.Line] = result
+	}
+
+	if len(results) != 6 {
+		t.Fatalf("Size was incorrect, got: %d, expected: 6", len(results))
+	}
+
+	expected := map[int]string{
+		1: BatchStatusOK,
+		4: BatchStatusOK,
+		5: BatchStatusError,
+		6: BatchStatusError,
+		7: BatchStatusOK,
+		8: BatchStatusOK,
+	}
+	for line, status := range expected {
+		result := results[line]
+		if result.Status != status {
+			t.Errorf("Status of line %d was incorrect, got: %s (%+v), expected: %s", line, result.Status, result.Error, status)
+		}
+		if status == BatchStatusError && result.Error == nil {
+			t.Errorf("Error of line %d was empty", line)
+		}
+	}
+
+	if results[4].Query != "iron man" || results[4].Provider != RottenT {
+		t.Errorf("Request of line 4 was incorrect, got: %+v", results[4].Request)
+	}
+
+	if results[7].ID != "tt0371746" || results[8].ID != "iron_man" {
+		t.Errorf("Requests of lines 7 and 8 were incorrect, got: %+v, %+v", results[7].Request, results[8].Request)
+	}
+}
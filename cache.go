@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CacheHit and CacheMiss tell whether a response was served by CacheFetcher
+// from disk or retrieved from the upstream fetcher
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+type (
+	// CacheFetcher stores the responses of the upstream fetcher on disk, so
+	// repeated lookups within their time to live don't hit providers again
+	CacheFetcher struct {
+		Dir      string
+		Upstream Fetcher
+		// TTLs is the time to live of responses per operation
+		TTLs map[string]time.Duration
+		// MaxAge overrides TTLs when greater than zero
+		MaxAge time.Duration
+		// Bypass are the operations whose responses are always retrieved from
+		// upstream. They're still stored for other runs.
+		Bypass []string
+	}
+)
+
+// DefaultCacheTTLs is the time to live of responses per operation. Search
+// suggestions change often while scores are fine for a day.
+var DefaultCacheTTLs = map[string]time.Duration{
+	opSearch: time.Hour,
+	opScore:  24 * time.Hour,
+}
+
+// searchURLs are the prefixes of URLs requested by search operations. Any
+// other URL is a page requested by score operations.
+var searchURLs = []string{
+	imdbAPIBaseURL,
+	rottenAPIBaseURL + "search",
+}
+
+// NewCacheFetcher creates a new instance of CacheFetcher with default TTLs
+func NewCacheFetcher(dir string, upstream Fetcher) *CacheFetcher {
+	ttls := make(map[string]time.Duration)
+	for op, ttl := range DefaultCacheTTLs {
+		ttls[op] = ttl
+	}
+
+	return &CacheFetcher{
+		Dir:      dir,
+		Upstream: upstream,
+		TTLs:     ttls,
+	}
+}
+
+// DefaultCacheDir returns the directory used to cache responses when none is
+// given, or an empty string if the user has no cache directory
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "movie-scores")
+}
+
+// Get returns the cached response for the given URL if it's still fresh,
+// otherwise it retrieves the response from upstream and caches it
+func (f *CacheFetcher) Get(ctx context.Context, url string) (*Response, error) {
+	filename := filepath.Join(f.Dir, cacheKey(url))
+
+	ttl := f.ttl(url)
+	if ttl > 0 {
+		info, err := os.Stat(filename)
+		if err == nil && time.Since(info.ModTime()) < ttl {
+			body, err := ioutil.ReadFile(filename)
+			if err == nil {
+				DefaultLogger.Debug("cache hit", "url", url, "age", time.Since(info.ModTime()).Round(time.Second), "ttl", ttl)
+				return &Response{URL: url, Body: body, Cache: CacheHit}, nil
+			}
+		}
+	}
+	DefaultLogger.Debug("cache miss", "url", url, "ttl", ttl)
+
+	response, err := f.Upstream.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: caching is best effort, a response that couldn't be stored is
+	// still a valid response.
+	if err := f.store(filename, response.Body); err != nil {
+		DefaultLogger.Debug("cache store failed", "url", url, "error", err)
+	}
+
+	response.Cache = CacheMiss
+	return response, nil
+}
+
+func (f *CacheFetcher) ttl(url string) time.Duration {
+	op := operationOf(url)
+	if isArgValid(op, f.Bypass) {
+		return 0
+	}
+	if f.MaxAge > 0 {
+		return f.MaxAge
+	}
+	return f.TTLs[op]
+}
+
+// store writes the body to a temporary file first, so concurrent lookups
+// never read a partially written entry
+func (f *CacheFetcher) store(filename string, body []byte) error {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return err
+	}
+
+	tmpfile, err := ioutil.TempFile(f.Dir, "tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write(body); err != nil {
+		tmpfile.Close()
+		return err
+	}
+	if err := tmpfile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpfile.Name(), filename)
+}
+
+// operationOf returns the operation that requests the given URL
+func operationOf(url string) string {
+	for _, prefix := range searchURLs {
+		if strings.HasPrefix(url, prefix) {
+			return opSearch
+		}
+	}
+	return opScore
+}
+
+func cacheKey(url string) string {
+	sum := sha1.Sum([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseTTLs parses a list of time to live per operation separated by commas,
+// e.g. search=30m,score=12h
+func parseTTLs(value string) (map[string]time.Duration, error) {
+	ops := make([]string, 0, len(DefaultCacheTTLs))
+	for op := range DefaultCacheTTLs {
+		ops = append(ops, op)
+	}
+	return parsePairs(value, "ttl", "operation=duration", ops, time.ParseDuration)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+type countingFetcher struct {
+	Fetcher
+	calls int
+}
+
+func (f *countingFetcher) Get(ctx context.Context, url string) (*Response, error) {
+	f.calls++
+	return f.Fetcher.Get(ctx, url)
+}
+
+func TestCacheFetcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	upstream := &countingFetcher{Fetcher: testFetcher}
+	cache := NewCacheFetcher(dir, upstream)
+
+	url := imdbBaseURL + "title/tt0371746"
+	response, err := cache.Get(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Cache != CacheMiss {
+		t.Errorf("Cache was incorrect, got: %s, expected: %s", response.Cache, CacheMiss)
+	}
+
+	response, err = cache.Get(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Cache != CacheHit {
+		t.Errorf("Cache was incorrect, got: %s, expected: %s", response.Cache, CacheHit)
+	}
+	if upstream.calls != 1 {
+		t.Errorf("Upstream calls were incorrect, got: %d, expected: 1", upstream.calls)
+	}
+
+	// Every entry is stale with such a short max age
+	cache.MaxAge = time.Nanosecond
+	response, err = cache.Get(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Cache != CacheMiss || upstream.calls != 2 {
+		t.Errorf("Stale entry was served, got: %s after %d calls", response.Cache, upstream.calls)
+	}
+}
+
+func TestCacheFetcherBypass(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	upstream := &countingFetcher{Fetcher: testFetcher}
+	cache := NewCacheFetcher(dir, upstream)
+	cache.Bypass = []string{opScore}
+
+	// Bypassed responses are still stored for runs that don't bypass them
+	url := imdbBaseURL + "title/tt0371746"
+	for i := 0; i < 2; i++ {
+		response, err := cache.Get(context.Background(), url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Cache != CacheMiss {
+			t.Errorf("Cache was incorrect, got: %s, expected: %s", response.Cache, CacheMiss)
+		}
+	}
+	if upstream.calls != 2 {
+		t.Errorf("Upstream calls were incorrect, got: %d, expected: 2", upstream.calls)
+	}
+
+	cache.Bypass = nil
+	response, err := cache.Get(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Cache != CacheHit {
+		t.Errorf("Cache was incorrect, got: %s, expected: %s", response.Cache, CacheHit)
+	}
+}
+
+func TestCacheMeta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rotten := NewRottenTomatoes(NewCacheFetcher(dir, testFetcher))
+
+	result, err := rotten.Score(context.Background(), "/m/sharknado_2013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Meta == nil || result.Meta.Cache != CacheMiss {
+		t.Errorf("Meta was incorrect, got: %+v", result.Meta)
+	}
+
+	result, err = rotten.Score(context.Background(), "/m/sharknado_2013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Meta == nil || result.Meta.Cache != CacheHit {
+		t.Errorf("Meta was incorrect, got: %+v", result.Meta)
+	}
+}
+
+func TestParseTTLs(t *testing.T) {
+	ttls, err := parseTTLs("search=30m, score=12h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ttls[opSearch] != 30*time.Minute || ttls[opScore] != 12*time.Hour {
+		t.Errorf("TTLs were incorrect, got: %v", ttls)
+	}
+
+	invalid := []string{"search", "match=1h", "score=tomorrow"}
+	for _, value := range invalid {
+		if _, err := parseTTLs(value); err == nil {
+			t.Errorf("Error was expected for '%s'", value)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// detailsCastSize is the number of top billed actors in a details result
+const detailsCastSize = 10
+
+// releaseDateLayout is the format of release dates in a details result
+const releaseDateLayout = "2006-01-02"
+
+type (
+	// linkedMovie represents the schema.org Movie embedded by providers in
+	// application/ld+json scripts. Only the fields used in details are parsed.
+	linkedMovie struct {
+		Type          linkedStrings `json:"@type"`
+		Name          string        `json:"name"`
+		AlternateName string        `json:"alternateName"`
+		Image         string        `json:"image"`
+		Genre         linkedStrings `json:"genre"`
+		ContentRating string        `json:"contentRating"`
+		Director      linkedPersons `json:"director"`
+		Actor         linkedPersons `json:"actor"`
+		// Actors is used by RottenTomatoes instead of actor
+		Actors        linkedPersons `json:"actors"`
+		Description   string        `json:"description"`
+		DatePublished string        `json:"datePublished"`
+		DateCreated   string        `json:"dateCreated"`
+		Duration      string        `json:"duration"`
+	}
+
+	// linkedStrings is either a single string or a list of them
+	linkedStrings []string
+
+	// linkedPersons is either a single Person or a list of them, only their
+	// names are kept
+	linkedPersons []string
+)
+
+var isoDurationPart = regexp.MustCompile(`(\d+)([HM])`)
+
+// findLinkedMovie returns the first Movie or TVSeries found in the
+// application/ld+json scripts of the document, or nil if there's none
+func findLinkedMovie(doc *goquery.Document) *linkedMovie {
+	var result *linkedMovie
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		var movie linkedMovie
+		if err := json.Unmarshal([]byte(s.Text()), &movie); err != nil {
+			return true
+		}
+		if !isArgValid("Movie", movie.Type) && !isArgValid("TVSeries", movie.Type) {
+			return true
+		}
+
+		result = &movie
+		return false
+	})
+	return result
+}
+
+// details converts the linked movie to a details result, leaving empty what
+// the provider doesn't embed
+func (m *linkedMovie) details(provider, id string) *DetailsResult {
+	result := &DetailsResult{
+		Provider:      provider,
+		ID:            id,
+		Kind:          linkedKind(m.Type),
+		Title:         html.UnescapeString(m.Name),
+		OriginalTitle: html.UnescapeString(m.AlternateName),
+		Runtime:       parseRuntime(m.Duration),
+		Genres:        []string(m.Genre),
+		ContentRating: m.ContentRating,
+		Directors:     []string(m.Director),
+		Cast:          []string(m.Actor),
+		Synopsis:      html.UnescapeString(m.Description),
+		Poster:        m.Image,
+		ReleaseDate:   parseReleaseDate(m.DatePublished),
+	}
+
+	if len(result.Cast) == 0 {
+		result.Cast = []string(m.Actors)
+	}
+	if result.ReleaseDate == "" {
+		result.ReleaseDate = parseReleaseDate(m.DateCreated)
+	}
+
+	return result
+}
+
+// complete fills the empty fields of the result with the values extracted by
+// DefaultSelectors from the page of the given provider
+func (result *DetailsResult) complete(doc *goquery.Document, provider string) {
+	fill := func(value *string, field string) {
+		if *value == "" {
+			*value = DefaultSelectors.Extract(doc, provider, field)
+		}
+	}
+
+	fill(&result.Title, "title")
+	fill(&result.ContentRating, "content_rating")
+	fill(&result.Synopsis, "synopsis")
+	fill(&result.Poster, "poster")
+
+	if result.ReleaseDate == "" {
+		result.ReleaseDate = parseReleaseDate(DefaultSelectors.Extract(doc, provider, "release_date"))
+	}
+	if result.Runtime == 0 {
+		result.Runtime = scoreAsInt(DefaultSelectors.Extract(doc, provider, "runtime"))
+	}
+	if result.Year == 0 {
+		result.Year = scoreAsInt(DefaultSelectors.Extract(doc, provider, "year"))
+	}
+	if result.Year == 0 && len(result.ReleaseDate) >= 4 {
+		year, _ := strconv.Atoi(result.ReleaseDate[:4])
+		result.Year = uint(year)
+	}
+
+	if len(result.Cast) > detailsCastSize {
+		result.Cast = result.Cast[:detailsCastSize]
+	}
+}
+
+// parseRuntime returns the minutes of an ISO 8601 duration like PT2H6M.
+// RottenTomatoes writes minutes without the time designator, e.g. P126M.
+func parseRuntime(duration string) uint {
+	var minutes uint
+	for _, match := range isoDurationPart.FindAllStringSubmatch(duration, -1) {
+		n, _ := strconv.Atoi(match[1])
+		if match[2] == "H" {
+			minutes += uint(n) * 60
+		} else {
+			minutes += uint(n)
+		}
+	}
+	return minutes
+}
+
+// parseReleaseDate returns the date in the given text as YYYY-MM-DD or an
+// empty string if it isn't a known date format. Anything after the first
+// line or an opening parenthesis is ignored, e.g. "2 May 2008 (USA)".
+func parseReleaseDate(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexAny(text, "\n("); i >= 0 {
+		text = strings.TrimSpace(text[:i])
+	}
+
+	layouts := []string{releaseDateLayout, time.RFC3339, "Jan 2, 2006", "January 2, 2006", "2 January 2006", "2 Jan 2006"}
+	for _, layout := range layouts {
+		if date, err := time.Parse(layout, text); err == nil {
+			return date.Format(releaseDateLayout)
+		}
+	}
+	return ""
+}
+
+// UnmarshalJSON accepts a string or a list of strings
+func (s *linkedStrings) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*s = list
+		return nil
+	}
+
+	var single string
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	*s = linkedStrings{single}
+	return nil
+}
+
+// UnmarshalJSON accepts a Person or a list of them and keeps their names
+func (p *linkedPersons) UnmarshalJSON(data []byte) error {
+	type person struct {
+		Name string `json:"name"`
+	}
+
+	var list []person
+	if err := json.Unmarshal(data, &list); err != nil {
+		var single person
+		if err := json.Unmarshal(data, &single); err != nil {
+			return err
+		}
+		list = []person{single}
+	}
+
+	names := make([]string, 0, len(list))
+	for _, person := range list {
+		if person.Name != "" {
+			names = append(names, html.UnescapeString(person.Name))
+		}
+	}
+	*p = names
+	return nil
+}
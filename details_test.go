@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestImdbDetails(t *testing.T) {
+	result, err := NewIMDb(testFetcher).Details(context.Background(), "tt0371746")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &DetailsResult{
+		Provider:      IMDB,
+		ID:            "tt0371746",
+		Kind:          KindMovie,
+		Title:         "Iron Man",
+		Year:          2008,
+		Runtime:       126,
+		Genres:        []string{"Action", "Adventure", "Sci-Fi"},
+		ContentRating: "PG-13",
+		Directors:     []string{"Jon Favreau"},
+		ReleaseDate:   "2008-05-02",
+	}
+	assertDetails(t, result, expected)
+
+	if len(result.Cast) == 0 || result.Cast[0] != "Robert Downey Jr." {
+		t.Errorf("Cast was incorrect, got: %v", result.Cast)
+	}
+}
+
+func TestRottenDetails(t *testing.T) {
+	result, err := NewRottenTomatoes(testFetcher).Details(context.Background(), "sharknado_2013")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &DetailsResult{
+		Provider:      RottenT,
+		ID:            "/m/sharknado_2013",
+		Kind:          KindMovie,
+		Title:         "Sharknado",
+		Year:          2013,
+		Runtime:       86,
+		Genres:        []string{"Action", "Horror", "Science Fiction"},
+		ContentRating: "NR",
+		Directors:     []string{"Anthony C. Ferrante"},
+		ReleaseDate:   "2013-07-11",
+	}
+	assertDetails(t, result, expected)
+
+	if result.Synopsis == "" || result.Poster == "" {
+		t.Errorf("Synopsis and poster were empty, got: %+v", result)
+	}
+}
+
+func TestParseRuntime(t *testing.T) {
+	cases := map[string]uint{
+		"PT2H6M": 126,
+		"PT126M": 126,
+		"P86M":   86,
+		"PT2H":   120,
+		"":       0,
+	}
+
+	for duration, expected := range cases {
+		if minutes := parseRuntime(duration); minutes != expected {
+			t.Errorf("Runtime of %s was incorrect, got: %d, expected: %d", duration, minutes, expected)
+		}
+	}
+}
+
+func TestParseReleaseDate(t *testing.T) {
+	cases := map[string]string{
+		"2008-05-02":              "2008-05-02",
+		"2 May 2008 (USA)":        "2008-05-02",
+		"Jul 11, 2013\n  limited": "2013-07-11",
+		"2013-07-11T17:00:00Z":    "2013-07-11",
+		"sometime in the 80s":     "",
+	}
+
+	for text, expected := range cases {
+		if date := parseReleaseDate(text); date != expected {
+			t.Errorf("Date of %q was incorrect, got: %s, expected: %s", text, date, expected)
+		}
+	}
+}
+
+func assertDetails(t *testing.T, result, expected *DetailsResult) {
+	got := *result
+	got.Cast, got.Synopsis, got.Poster, got.Meta = nil, "", "", nil
+	if !reflect.DeepEqual(&got, expected) {
+		t.Errorf("Details were incorrect, got: %+v, expected: %+v", got, *expected)
+	}
+}
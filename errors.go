@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Kinds of errors returned by providers. Use errors.Is to check the kind of a
+// returned error.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrBlocked      = errors.New("blocked")
+	ErrParseFailure = errors.New("parse failure")
+	ErrTimeout      = errors.New("timeout")
+	// ErrUpstream is any other unsuccessful response from a provider
+	ErrUpstream = errors.New("upstream error")
+)
+
+// Exit codes of the application, one per kind of error
+const (
+	ExitOK           = 0
+	ExitFailure      = 1
+	ExitNotFound     = 3
+	ExitRateLimited  = 4
+	ExitBlocked      = 5
+	ExitParseFailure = 6
+	ExitTimeout      = 7
+	ExitUpstream     = 8
+	// ExitSelftestFailed means a selftest operation found fields that can't
+	// be parsed anymore
+	ExitSelftestFailed = 9
+)
+
+type (
+	// ProviderError represents a failure while retrieving or parsing a page of
+	// a provider
+	ProviderError struct {
+		Kind       error
+		StatusCode int
+		URL        string
+		Err        error
+	}
+
+	// ErrorOutput represents an error in the output of the application
+	ErrorOutput struct {
+		Kind    string `json:"kind"`
+		Message string `json:"message"`
+		Status  int    `json:"status,omitempty"`
+		URL     string `json:"url,omitempty"`
+	}
+
+	errorKind struct {
+		err        error
+		name       string
+		exitCode   int
+		httpStatus int
+	}
+)
+
+var errorKinds = []errorKind{
+	{ErrNotFound, "not_found", ExitNotFound, http.StatusNotFound},
+	{ErrRateLimited, "rate_limited", ExitRateLimited, http.StatusServiceUnavailable},
+	{ErrBlocked, "blocked", ExitBlocked, http.StatusBadGateway},
+	{ErrParseFailure, "parse_failure", ExitParseFailure, http.StatusBadGateway},
+	{ErrTimeout, "timeout", ExitTimeout, http.StatusGatewayTimeout},
+	{ErrUpstream, "upstream", ExitUpstream, http.StatusBadGateway},
+}
+
+// unknownErrorKind is used for errors that don't match any kind, e.g. invalid
+// arguments
+var unknownErrorKind = errorKind{nil, "unknown", ExitFailure, http.StatusInternalServerError}
+
+func (e *ProviderError) Error() string {
+	msg := e.Kind.Error()
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("%s (status %d)", msg, e.StatusCode)
+	}
+	if e.URL != "" {
+		msg += ": " + e.URL
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Is reports whether the kind of the error is target
+func (e *ProviderError) Is(target error) bool {
+	return e.Kind == target
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// statusError returns the error for an unsuccessful HTTP status code
+func statusError(url string, statusCode int) error {
+	kind := ErrUpstream
+	switch statusCode {
+	case http.StatusNotFound, http.StatusGone:
+		kind = ErrNotFound
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		kind = ErrRateLimited
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusUnavailableForLegalReasons:
+		kind = ErrBlocked
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		kind = ErrTimeout
+	}
+
+	return &ProviderError{
+		Kind:       kind,
+		StatusCode: statusCode,
+		URL:        url,
+	}
+}
+
+// requestError classifies an error returned while performing a request
+func requestError(url string, err error) error {
+	if netErr, ok := err.(net.Error); (ok && netErr.Timeout()) || errors.Is(err, context.DeadlineExceeded) {
+		return &ProviderError{Kind: ErrTimeout, URL: url, Err: err}
+	}
+	return err
+}
+
+// parseError returns the error for a page whose contents couldn't be parsed
+func parseError(url string, err error) error {
+	return &ProviderError{Kind: ErrParseFailure, URL: url, Err: err}
+}
+
+func kindOf(err error) errorKind {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind
+		}
+	}
+	return unknownErrorKind
+}
+
+// exitCode returns the exit code of the application for the given error
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	return kindOf(err).exitCode
+}
+
+// newErrorOutput converts an error to its output format or returns nil if
+// there's no error
+func newErrorOutput(err error) *ErrorOutput {
+	if err == nil {
+		return nil
+	}
+
+	result := &ErrorOutput{
+		Kind:    kindOf(err).name,
+		Message: err.Error(),
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		result.Status = providerErr.StatusCode
+		result.URL = providerErr.URL
+	}
+
+	return result
+}
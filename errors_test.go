@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestStatusError(t *testing.T) {
+	cases := map[int]error{
+		http.StatusNotFound:            ErrNotFound,
+		http.StatusTooManyRequests:     ErrRateLimited,
+		http.StatusServiceUnavailable:  ErrRateLimited,
+		http.StatusForbidden:           ErrBlocked,
+		http.StatusGatewayTimeout:      ErrTimeout,
+		http.StatusInternalServerError: ErrUpstream,
+	}
+
+	for status, kind := range cases {
+		err := statusError("https://www.imdb.com/title/tt0371746", status)
+		if !errors.Is(err, kind) {
+			t.Errorf("Kind of status %d was incorrect, got: %v, expected: %v", status, err, kind)
+		}
+	}
+}
+
+func TestErrorOutput(t *testing.T) {
+	url := "https://www.rottentomatoes.com/m/iron_man"
+	err := fmt.Errorf("rotten search failed: %w", statusError(url, http.StatusTooManyRequests))
+
+	output := newErrorOutput(err)
+	if output.Kind != "rate_limited" || output.Status != http.StatusTooManyRequests || output.URL != url {
+		t.Errorf("Output was incorrect, got: %+v", output)
+	}
+
+	if code := exitCode(err); code != ExitRateLimited {
+		t.Errorf("Exit code was incorrect, got: %d, expected: %d", code, ExitRateLimited)
+	}
+
+	if code := exitCode(errors.New("invalid argument")); code != ExitFailure {
+		t.Errorf("Exit code was incorrect, got: %d, expected: %d", code, ExitFailure)
+	}
+}
+
+func TestParseFailure(t *testing.T) {
+	imdb := NewIMDb(stubFetcher{
+		imdbBaseURL + "title/tt0000001": "<html><body>Redesigned page</body></html>",
+	})
+
+	_, err := imdb.Score(context.Background(), "tt0000001")
+	if !errors.Is(err, ErrParseFailure) {
+		t.Errorf("Error was incorrect, got: %v, expected: %v", err, ErrParseFailure)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Fetcher is an interface used by providers to retrieve remote documents
+	Fetcher interface {
+		Get(ctx context.Context, url string) (*Response, error)
+	}
+
+	// Response represents a document retrieved by a Fetcher
+	Response struct {
+		URL  string
+		Body []byte
+		// Cache is either CacheHit or CacheMiss when the response went through
+		// a CacheFetcher, otherwise it's empty
+		Cache string
+		// Attempts is the number of requests needed to retrieve the response
+		// over HTTP, zero if it didn't come from the network
+		Attempts int
+	}
+
+	// HTTPFetcher retrieves documents over HTTP. Failed requests that may
+	// succeed later are retried with exponential backoff.
+	HTTPFetcher struct {
+		Client *http.Client
+		// Retries is the number of times a failed request is retried
+		Retries int
+		// RetryBaseWait is the wait before the first retry, it doubles on each
+		// following retry
+		RetryBaseWait time.Duration
+		// RetryMaxWait is the longest wait between two attempts. A request is
+		// not retried when the provider asks to wait longer than that.
+		RetryMaxWait time.Duration
+		// Limiter, when set, limits how fast requests are sent to each host
+		Limiter *HostLimiter
+		// CheckRobots refuses to fetch paths disallowed by robots.txt
+		CheckRobots bool
+
+		robotsMu sync.Mutex
+		robots   map[string]*robotsEntry
+	}
+
+	// ReplayFetcher serves responses previously saved in Dir. When Upstream is
+	// set, responses missing from Dir are fetched from it and recorded.
+	ReplayFetcher struct {
+		Dir      string
+		Upstream Fetcher
+	}
+)
+
+// DefaultFetcher is the fetcher used when a provider is created without one
+var DefaultFetcher Fetcher = NewHTTPFetcher()
+
+// DefaultRequestTimeout is the longest a single attempt of a request may take.
+// It's a backstop for stalled connections when the context has no deadline,
+// e.g. -timeout 0.
+const DefaultRequestTimeout = 10 * time.Second
+
+// NewHTTPFetcher creates a new instance of HTTPFetcher
+func NewHTTPFetcher() *HTTPFetcher {
+	return &HTTPFetcher{
+		Client:        newHTTPClient(DefaultRequestTimeout),
+		Retries:       2,
+		RetryBaseWait: 500 * time.Millisecond,
+		RetryMaxWait:  30 * time.Second,
+	}
+}
+
+// newHTTPClient returns a client whose requests give up after timeout, and
+// whose connections give up earlier when a host doesn't accept them or never
+// starts to respond
+func newHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = 5 * time.Second
+	transport.ResponseHeaderTimeout = timeout
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+}
+
+// Get performs a GET request to the given URL, retrying it while the context
+// isn't done. Canceling the context aborts the request.
+func (f *HTTPFetcher) Get(ctx context.Context, url string) (*Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "*/*")
+
+	if f.CheckRobots {
+		allowed, err := f.allowedByRobots(ctx, req.URL)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			DefaultLogger.Debug("disallowed by robots.txt", "url", url)
+			return nil, &ProviderError{
+				Kind: ErrBlocked,
+				URL:  url,
+				Err:  errors.New("disallowed by robots.txt"),
+			}
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		response, retryAfter, err := f.do(req)
+		if err == nil {
+			response.Attempts = attempt
+			return response, nil
+		}
+
+		if attempt > f.Retries || !isRetryable(err) {
+			DefaultLogger.Debug("giving up", "url", url, "attempts", attempt, "error", err)
+			return nil, err
+		}
+
+		wait := retryAfter
+		if wait > f.RetryMaxWait {
+			// Not worth waiting, the provider won't answer any time soon
+			DefaultLogger.Debug("giving up", "url", url, "attempts", attempt, "retry_after", wait, "error", err)
+			return nil, err
+		}
+		if wait == 0 {
+			wait = backoff(attempt, f.RetryBaseWait, f.RetryMaxWait)
+		}
+		DefaultLogger.Debug("retrying", "url", url, "attempt", attempt, "wait", wait, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, requestError(url, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
+
+// do performs a single attempt of the request. When the provider responds
+// with a Retry-After header, its value is returned along with the error.
+func (f *HTTPFetcher) do(req *http.Request) (*Response, time.Duration, error) {
+	url := req.URL.String()
+
+	if f.Limiter != nil {
+		if err := f.Limiter.Wait(req.Context(), req.URL.Host); err != nil {
+			return nil, 0, requestError(url, err)
+		}
+	}
+
+	// Rotate user agent on every attempt
+	req.Header.Set("User-Agent", GetRandomUserAgent())
+
+	start := time.Now()
+	response, err := f.Client.Do(req)
+	if err != nil {
+		DefaultLogger.Debug("request failed", "url", url, "latency", time.Since(start), "error", err)
+		return nil, 0, requestError(url, err)
+	}
+
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		DefaultLogger.Debug("request", "url", url, "status", response.StatusCode, "latency", time.Since(start))
+		// Blocked and failed pages are the ones worth tracing
+		if DefaultLogger.Enabled(LevelTrace) {
+			if body, err := ioutil.ReadAll(response.Body); err == nil {
+				DefaultLogger.Dump(url, response.StatusCode, body)
+			}
+		}
+		return nil, retryAfter(response.Header.Get("Retry-After")), statusError(url, response.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		DefaultLogger.Debug("request failed", "url", url, "status", response.StatusCode, "latency", time.Since(start), "error", err)
+		return nil, 0, requestError(url, err)
+	}
+	DefaultLogger.Debug("request", "url", url, "status", response.StatusCode, "latency", time.Since(start), "bytes", len(body))
+	DefaultLogger.Dump(url, response.StatusCode, body)
+
+	return &Response{URL: url, Body: body}, 0, nil
+}
+
+// Meta returns the information about how the response was retrieved or nil if
+// there's nothing to report
+func (r *Response) Meta() *Meta {
+	if r.Cache == "" && r.Attempts == 0 {
+		return nil
+	}
+	return &Meta{Cache: r.Cache, Attempts: r.Attempts}
+}
+
+// NewReplayFetcher creates a new instance of ReplayFetcher that only serves
+// responses already saved in dir
+func NewReplayFetcher(dir string) *ReplayFetcher {
+	return &ReplayFetcher{Dir: dir}
+}
+
+// NewRecordFetcher creates a new instance of ReplayFetcher that records every
+// response missing from dir using upstream
+func NewRecordFetcher(dir string, upstream Fetcher) *ReplayFetcher {
+	return &ReplayFetcher{Dir: dir, Upstream: upstream}
+}
+
+// Get returns the saved response for the given URL
+func (f *ReplayFetcher) Get(ctx context.Context, url string) (*Response, error) {
+	filename := filepath.Join(f.Dir, fixtureName(url))
+
+	body, err := ioutil.ReadFile(filename)
+	if err == nil {
+		DefaultLogger.Debug("fixture replayed", "url", url, "file", filename, "bytes", len(body))
+		return &Response{URL: url, Body: body}, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if f.Upstream == nil {
+		return nil, &ProviderError{
+			Kind: ErrNotFound,
+			URL:  url,
+			Err:  fmt.Errorf("no fixture in %s", f.Dir),
+		}
+	}
+
+	response, err := f.Upstream.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filename, response.Body, 0644); err != nil {
+		return nil, err
+	}
+	DefaultLogger.Debug("fixture recorded", "url", url, "file", filename)
+
+	return response, nil
+}
+
+// fixtureName converts an URL to the name of the file holding its response,
+// e.g. https://www.imdb.com/title/tt0371746 -> www.imdb.com_title_tt0371746
+func fixtureName(url string) string {
+	name := url
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+3:]
+	}
+
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+
+	return strings.Trim(name, "_")
+}
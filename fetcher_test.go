@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testFetcher serves the responses saved in testdata so tests run offline
+var testFetcher = NewReplayFetcher("testdata")
+
+type stubFetcher map[string]string
+
+func (f stubFetcher) Get(ctx context.Context, url string) (*Response, error) {
+	body, ok := f[url]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &Response{URL: url, Body: []byte(body)}, nil
+}
+
+func TestFixtureName(t *testing.T) {
+	cases := map[string]string{
+		"https://www.imdb.com/title/tt0371746":                            "www.imdb.com_title_tt0371746",
+		"https://www.rottentomatoes.com/napi/search/?limit=10&query=iron": "www.rottentomatoes.com_napi_search__limit_10_query_iron",
+	}
+
+	for url, expected := range cases {
+		if name := fixtureName(url); name != expected {
+			t.Errorf("Name was incorrect, got: %s, expected: %s", name, expected)
+		}
+	}
+}
+
+func TestReplayFetcherMissing(t *testing.T) {
+	_, err := testFetcher.Get(context.Background(), "https://www.imdb.com/title/tt0000000")
+	if err == nil {
+		t.Errorf("Error was expected for a missing fixture")
+	}
+}
+
+func TestRecordFetcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	url := "https://www.imdb.com/title/tt0000001"
+	upstream := stubFetcher{url: "<html></html>"}
+
+	response, err := NewRecordFetcher(dir, upstream).Get(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := ioutil.ReadFile(filepath.Join(dir, fixtureName(url)))
+	if err != nil {
+		t.Fatalf("Response was not recorded: %s", err.Error())
+	}
+
+	if string(saved) != string(response.Body) {
+		t.Errorf("Recorded contents was invalid, got: %s, expected: %s", saved, response.Body)
+	}
+
+	// Replaying must not need the upstream anymore
+	response, err = NewReplayFetcher(dir).Get(context.Background(), url)
+	if err != nil || string(response.Body) != "<html></html>" {
+		t.Errorf("Replay failed, got: %v, %v", response, err)
+	}
+}
+
+func TestHTTPFetcherAbort(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hang until the client gives up
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := NewHTTPFetcher().Get(ctx, server.URL)
+	if err == nil {
+		t.Errorf("Error was expected when context is canceled")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Request was not aborted when context was canceled")
+	}
+}
+
+func TestHTTPFetcherStalled(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Never respond, even when the client gives up
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	fetcher := NewHTTPFetcher()
+	fetcher.Client = newHTTPClient(50 * time.Millisecond)
+	fetcher.Retries = 0
+
+	start := time.Now()
+	_, err := fetcher.Get(context.Background(), server.URL)
+	if err == nil {
+		t.Errorf("Error was expected when the server stalls")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Request without deadline was not aborted by the client timeout")
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Formats of the output of operations
+const (
+	FormatJSON       = "json"
+	FormatJSONPretty = "json-pretty"
+	FormatNDJSON     = "ndjson"
+	FormatCSV        = "csv"
+	FormatTable      = "table"
+)
+
+var supportedFormats = []string{FormatJSON, FormatJSONPretty, FormatNDJSON, FormatCSV, FormatTable}
+
+// listSeparator joins the items of a list in a single CSV or table cell
+const listSeparator = "|"
+
+// Encode writes data to w in the given format. Line based formats write one
+// line per result of lists, e.g. the results of a search page, and a single
+// line otherwise. CSV and table flatten nested results into columns named
+// after their JSON fields, e.g. meta_cache.
+func Encode(w io.Writer, format string, data interface{}) error {
+	switch format {
+	case FormatJSON, "":
+		return json.NewEncoder(w).Encode(data)
+	case FormatJSONPretty:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	case FormatNDJSON:
+		rows := records(data)
+		encoder := json.NewEncoder(w)
+		for i := 0; i < rows.Len(); i++ {
+			if err := encoder.Encode(rows.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		header, lines := flatten(records(data))
+		writer := csv.NewWriter(w)
+		writer.Write(header)
+		writer.WriteAll(lines)
+		return writer.Error()
+	case FormatTable:
+		header, lines := flatten(records(data))
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for i := range header {
+			header[i] = strings.ToUpper(header[i])
+		}
+		fmt.Fprintln(writer, strings.Join(header, "\t"))
+		for _, line := range lines {
+			fmt.Fprintln(writer, strings.Join(line, "\t"))
+		}
+		return writer.Flush()
+	}
+
+	return fmt.Errorf("format '%s' is not supported", format)
+}
+
+// records returns the rows of data as a slice. Results wrapping a list are
+// unwrapped and any other value is a single row.
+func records(data interface{}) reflect.Value {
+	var rows interface{}
+	switch d := data.(type) {
+	case *SearchPage:
+		rows = d.Results
+	case *MultiScoreResult:
+		rows = d.Results
+	case *MatchResult:
+		rows = d.Scores
+	case *FranchiseResult:
+		rows = d.Titles
+	case *SelftestReport:
+		rows = d.Checks
+	case *HistoryResult:
+		rows = d.Entries
+	case *DiffResult:
+		rows = d.Changes
+	default:
+		rows = data
+	}
+
+	v := reflect.ValueOf(rows)
+	if v.Kind() == reflect.Slice {
+		return v
+	}
+
+	single := reflect.MakeSlice(reflect.SliceOf(v.Type()), 1, 1)
+	single.Index(0).Set(v)
+	return single
+}
+
+// flatten returns the columns of the element type of rows and the cells of
+// each row, in the order fields are declared
+func flatten(rows reflect.Value) ([]string, [][]string) {
+	header := columns(rows.Type().Elem(), "")
+
+	lines := make([][]string, 0, rows.Len())
+	for i := 0; i < rows.Len(); i++ {
+		lines = append(lines, cells(rows.Index(i), rows.Type().Elem()))
+	}
+	return header, lines
+}
+
+// columns returns the column names of a type. Nested structs add a column per
+// field prefixed by the name of the struct field.
+func columns(t reflect.Type, prefix string) []string {
+	if !isFlatStruct(t) {
+		return []string{strings.TrimSuffix(prefix, "_")}
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	result := make([]string, 0)
+	eachField(t, func(i int, name string) {
+		field := t.Field(i)
+		if isFlatStruct(field.Type) {
+			result = append(result, columns(field.Type, fieldPrefix(prefix, name))...)
+		} else if isCell(field.Type) {
+			result = append(result, prefix+name)
+		}
+	})
+	return result
+}
+
+// cells returns the values of v for each column of its type t. A nil nested
+// result has empty cells.
+func cells(v reflect.Value, t reflect.Type) []string {
+	if !isFlatStruct(t) {
+		return []string{cell(v)}
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if v.IsNil() {
+			return make([]string, len(columns(t, "")))
+		}
+		v = v.Elem()
+	}
+
+	result := make([]string, 0)
+	eachField(t, func(i int, name string) {
+		field := t.Field(i)
+		if isFlatStruct(field.Type) {
+			result = append(result, cells(v.Field(i), field.Type)...)
+		} else if isCell(field.Type) {
+			result = append(result, cell(v.Field(i)))
+		}
+	})
+	return result
+}
+
+// eachField calls fn with every exported field of t that is part of its JSON
+// and the name of the field in JSON. Embedded structs have no name, like in
+// JSON their fields belong to the outer struct.
+func eachField(t reflect.Type, fn func(i int, name string)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" && !field.Anonymous {
+			name = field.Name
+		}
+		fn(i, name)
+	}
+}
+
+func fieldPrefix(prefix, name string) string {
+	if name == "" {
+		return prefix
+	}
+	return prefix + name + "_"
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// isFlatStruct reports whether the type is a struct whose fields become
+// columns. Structs with a text form, e.g. time.Time, are a single cell.
+func isFlatStruct(t reflect.Type) bool {
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// isCell reports whether values of the type fit in a single cell. Lists of
+// structs, e.g. a rating distribution, don't.
+func isCell(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	case reflect.Map, reflect.Chan, reflect.Func:
+		return false
+	}
+	return true
+}
+
+func cell(v reflect.Value) string {
+	if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+		text, _ := marshaler.MarshalText()
+		return string(text)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).String()
+		}
+		return strings.Join(items, listSeparator)
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return ""
+		}
+		return cell(v.Elem())
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeCSV(t *testing.T) {
+	page := &SearchPage{
+		Total: 2,
+		Limit: 10,
+		Results: []SearchResult{
+			{Provider: RottenT, ID: "/m/iron_man", Kind: KindMovie, Title: "Iron Man", Score: 94, NormalizedScore: 94, Year: 2008, Meta: &Meta{Cache: CacheHit}},
+			{Provider: RottenT, ID: "/m/iron_man_2", Kind: KindMovie, Title: "Iron Man 2, The Sequel", Score: 72.5, NormalizedScore: 72.5, Year: 2010},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, FormatCSV, page); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{"provider", "id", "kind", "title", "poster", "score", "normalized_score", "score_class", "year", "end_year", "match_score", "meta_cache", "meta_attempts"},
+		{"rotten", "/m/iron_man", "movie", "Iron Man", "", "94", "94", "", "2008", "0", "", "hit", "0"},
+		{"rotten", "/m/iron_man_2", "movie", "Iron Man 2, The Sequel", "", "72.5", "72.5", "", "2010", "0", "", "", ""},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("CSV was incorrect, got: %v, expected: %v", lines, expected)
+	}
+}
+
+func TestEncodeNDJSON(t *testing.T) {
+	result := &MultiScoreResult{
+		Results: []ProviderScoreResult{
+			{Provider: IMDB, ID: "tt0371746", Result: &ScoreResult{Provider: IMDB, ID: "tt0371746", Score: 7.9}},
+			{Provider: RottenT, ID: "/m/iron_man", Error: &ErrorOutput{Kind: "not_found", Message: "not found"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, FormatNDJSON, result); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Line count was incorrect, got: %d, expected: 2", len(lines))
+	}
+	if !strings.Contains(lines[1], `"kind":"not_found"`) {
+		t.Errorf("Second line was incorrect, got: %s", lines[1])
+	}
+}
+
+func TestEncodeTable(t *testing.T) {
+	score := &ScoreResult{Provider: IMDB, ID: "tt0371746", Score: 7.9, Distribution: []RatingVotes{{Rating: 10, Votes: 1}}}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, FormatTable, score); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Line count was incorrect, got: %d, expected: 2", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "PROVIDER  ID") || strings.Contains(lines[0], "DISTRIBUTION") {
+		t.Errorf("Header was incorrect, got: %s", lines[0])
+	}
+	if strings.Index(lines[0], "SCORE") != strings.Index(lines[1], "7.9") {
+		t.Errorf("Columns weren't aligned, got:\n%s", buf.String())
+	}
+}
+
+func TestEncodeUnsupported(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, "xml", &ScoreResult{}); err == nil {
+		t.Errorf("Unsupported format was encoded")
+	}
+}
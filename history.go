@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultHistoryThreshold is the smallest change of a normalized score that
+// diff operations report
+const DefaultHistoryThreshold = 10
+
+type (
+	// HistoryStore keeps every score retrieved in a file, one JSON entry per
+	// line, so scores can be compared between runs
+	HistoryStore struct {
+		Path string
+
+		mu sync.Mutex
+	}
+
+	// HistoryEntry represents a score retrieved at a given time
+	HistoryEntry struct {
+		Time            time.Time `json:"time"`
+		Provider        string    `json:"provider"`
+		ID              string    `json:"id"`
+		Score           float32   `json:"score"`
+		NormalizedScore float32   `json:"normalized_score"`
+		Votes           uint      `json:"votes,omitempty"`
+	}
+
+	// HistoryResult represents the result for a history operation, which is
+	// every score of a title from the oldest to the newest
+	HistoryResult struct {
+		Provider string         `json:"provider"`
+		ID       string         `json:"id"`
+		Entries  []HistoryEntry `json:"entries"`
+	}
+
+	// DiffResult represents the result for a diff operation, which lists the
+	// titles whose normalized score changed by more than Threshold since the
+	// previous run
+	DiffResult struct {
+		Threshold float32         `json:"threshold"`
+		Changes   []HistoryChange `json:"changes"`
+	}
+
+	// HistoryChange represents the change of score of a title between its two
+	// latest entries
+	HistoryChange struct {
+		Provider      string    `json:"provider"`
+		ID            string    `json:"id"`
+		PreviousScore float32   `json:"previous_score"`
+		Score         float32   `json:"score"`
+		Change        float32   `json:"change"`
+		PreviousTime  time.Time `json:"previous_time"`
+		Time          time.Time `json:"time"`
+	}
+)
+
+// DefaultHistory is where every score is recorded, nil disables the history
+var DefaultHistory *HistoryStore
+
+// NewHistoryStore creates a new instance of HistoryStore that keeps entries
+// in the given file
+func NewHistoryStore(path string) *HistoryStore {
+	return &HistoryStore{Path: path}
+}
+
+// Record appends the given score to the history. Recording to a nil store
+// does nothing.
+func (h *HistoryStore) Record(result *ScoreResult) error {
+	if h == nil || result == nil {
+		return nil
+	}
+
+	entry := HistoryEntry{
+		Time:            time.Now().UTC(),
+		Provider:        result.Provider,
+		ID:              result.ID,
+		Score:           result.Score,
+		NormalizedScore: result.NormalizedScore,
+		Votes:           votesOf(result),
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := os.OpenFile(h.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, outputPerm)
+	if err != nil {
+		return err
+	}
+	// A single write per entry, so concurrent runs don't mix their lines
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Series returns every entry of the given title from the oldest to the newest.
+// The id is the same given to the Score of the provider.
+func (h *HistoryStore) Series(provider, id string) (*HistoryResult, error) {
+	entries, err := h.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Scores are recorded with the id returned by the provider
+	if provider == RottenT {
+		id = rottenPath(id)
+	}
+
+	result := &HistoryResult{
+		Provider: provider,
+		ID:       id,
+		Entries:  make([]HistoryEntry, 0),
+	}
+	for _, entry := range entries {
+		if entry.Provider == provider && entry.ID == id {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+
+	if len(result.Entries) == 0 {
+		return nil, &ProviderError{Kind: ErrNotFound, Err: errors.New("no history for " + provider + " " + id)}
+	}
+	return result, nil
+}
+
+// Diff compares the two latest entries of every title, optionally of a
+// single provider, and returns the ones whose normalized score changed by
+// more than threshold. Biggest changes come first.
+func (h *HistoryStore) Diff(provider string, threshold float32) (*DiffResult, error) {
+	entries, err := h.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct{ provider, id string }
+	latest := make(map[key][2]*HistoryEntry)
+	order := make([]key, 0)
+	for i := range entries {
+		entry := &entries[i]
+		if provider != "" && provider != AllProviders && entry.Provider != provider {
+			continue
+		}
+
+		k := key{entry.Provider, entry.ID}
+		last, ok := latest[k]
+		if !ok {
+			order = append(order, k)
+		}
+		latest[k] = [2]*HistoryEntry{last[1], entry}
+	}
+
+	result := &DiffResult{
+		Threshold: threshold,
+		Changes:   make([]HistoryChange, 0),
+	}
+	for _, k := range order {
+		previous, current := latest[k][0], latest[k][1]
+		if previous == nil {
+			continue
+		}
+
+		change := current.NormalizedScore - previous.NormalizedScore
+		if float32(math.Abs(float64(change))) <= threshold {
+			continue
+		}
+
+		result.Changes = append(result.Changes, HistoryChange{
+			Provider:      k.provider,
+			ID:            k.id,
+			PreviousScore: previous.NormalizedScore,
+			Score:         current.NormalizedScore,
+			Change:        change,
+			PreviousTime:  previous.Time,
+			Time:          current.Time,
+		})
+	}
+
+	sort.SliceStable(result.Changes, func(i, j int) bool {
+		return math.Abs(float64(result.Changes[i].Change)) > math.Abs(float64(result.Changes[j].Change))
+	})
+
+	return result, nil
+}
+
+// entries reads every entry of the history in the order they were recorded.
+// Lines that can't be read, e.g. cut by a crash, are skipped.
+func (h *HistoryStore) entries() ([]HistoryEntry, error) {
+	if h == nil {
+		return nil, errors.New("history is disabled, use -history to set its file")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := os.Open(h.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	result := make([]HistoryEntry, 0)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			DefaultLogger.Debug("history line skipped", "file", h.Path, "line", line, "error", err)
+			continue
+		}
+		result = append(result, entry)
+	}
+
+	return result, scanner.Err()
+}
+
+// recordScore records the given score in DefaultHistory. The score is still
+// valid when it can't be recorded, so failures are only logged.
+func recordScore(result *ScoreResult) {
+	if result == nil {
+		return
+	}
+	if err := DefaultHistory.Record(result); err != nil {
+		DefaultLogger.Error("history record failed", "provider", result.Provider, "id", result.ID, "error", err)
+	}
+}
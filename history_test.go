@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempHistory(t *testing.T) (*HistoryStore, func()) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewHistoryStore(filepath.Join(dir, "history.jsonl")), func() { os.RemoveAll(dir) }
+}
+
+func TestHistoryRecordedOnScore(t *testing.T) {
+	store, cleanup := tempHistory(t)
+	defer cleanup()
+
+	defer func(history *HistoryStore) { DefaultHistory = history }(DefaultHistory)
+	DefaultHistory = store
+
+	requests := []Request{
+		{Provider: IMDB, Operation: opScore, ID: "tt0371746"},
+		{Provider: AllProviders, Operation: opScore, ID: "imdb=tt0371746,rotten=iron_man"},
+		// Every operation retrieving scores records them
+		{Provider: AllProviders, Operation: opAggregate, ID: "imdb=tt0371746"},
+		{Provider: AllProviders, Operation: opMatch, Query: "iron man", Year: 2008},
+		// Franchise scores come from the franchise page, not from score
+		{Provider: RottenT, Operation: opFranchise, ID: "/franchise/iron_man"},
+	}
+	for _, req := range requests {
+		if _, err := req.execute(context.Background(), testFetcher); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := store.Series(IMDB, "tt0371746")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Entries) != 4 {
+		t.Fatalf("Number of entries was incorrect, got: %d, expected: 4", len(result.Entries))
+	}
+	entry := result.Entries[0]
+	if entry.Score != 7.9 || entry.NormalizedScore != 79 || entry.Votes != 881325 || entry.Time.IsZero() {
+		t.Errorf("Entry was incorrect, got: %+v", entry)
+	}
+
+	// Rotten IDs are recorded as page paths, any id given to score works
+	for _, id := range []string{"/m/iron_man", "iron_man", "m/iron_man"} {
+		if _, err := store.Series(RottenT, id); err != nil {
+			t.Errorf("Rotten history of %s failed: %s", id, err.Error())
+		}
+	}
+
+	if _, err := store.Series(IMDB, "tt0000000"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Error was incorrect, got: %v, expected: %v", err, ErrNotFound)
+	}
+}
+
+func TestHistoryRecordedOnServerScore(t *testing.T) {
+	store, cleanup := tempHistory(t)
+	defer cleanup()
+
+	defer func(history *HistoryStore) { DefaultHistory = history }(DefaultHistory)
+	DefaultHistory = store
+
+	handler := NewServer(testFetcher).Handler()
+	for _, target := range []string{
+		"/score?provider=imdb&id=tt0371746",
+		"/score?provider=all&id=imdb=tt0371746,rotten=iron_man",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Status of %s was incorrect, got: %d, expected: %d", target, rec.Code, http.StatusOK)
+		}
+	}
+
+	imdb, err := store.Series(IMDB, "tt0371746")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imdb.Entries) != 2 {
+		t.Errorf("Number of entries was incorrect, got: %d, expected: 2", len(imdb.Entries))
+	}
+
+	if _, err := store.Series(RottenT, "/m/iron_man"); err != nil {
+		t.Errorf("Rotten history failed: %s", err.Error())
+	}
+}
+
+func TestHistoryDiff(t *testing.T) {
+	store, cleanup := tempHistory(t)
+	defer cleanup()
+
+	runs := [][]*ScoreResult{
+		{
+			{Provider: RottenT, ID: "/m/iron_man", Score: 94, NormalizedScore: 94},
+			{Provider: IMDB, ID: "tt0371746", Score: 7.9, NormalizedScore: 79},
+			{Provider: IMDB, ID: "tt1228705", Score: 7, NormalizedScore: 70},
+		},
+		{
+			// A scraper that broke
+			{Provider: RottenT, ID: "/m/iron_man", Score: 0, NormalizedScore: 0},
+			{Provider: IMDB, ID: "tt0371746", Score: 7.8, NormalizedScore: 78},
+			{Provider: IMDB, ID: "tt1228705", Score: 5.5, NormalizedScore: 55},
+		},
+	}
+	for _, run := range runs {
+		for _, score := range run {
+			if err := store.Record(score); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	result, err := store.Diff(AllProviders, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changes) != 2 {
+		t.Fatalf("Changes were incorrect, got: %+v", result.Changes)
+	}
+	first, second := result.Changes[0], result.Changes[1]
+	if first.ID != "/m/iron_man" || first.Change != -94 || second.ID != "tt1228705" || second.Change != -15 {
+		t.Errorf("Changes were incorrect, got: %+v", result.Changes)
+	}
+
+	result, err = store.Diff(IMDB, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changes) != 2 || result.Changes[1].ID != "tt0371746" {
+		t.Errorf("Changes of imdb were incorrect, got: %+v", result.Changes)
+	}
+}
+
+func TestHistoryDisabled(t *testing.T) {
+	var store *HistoryStore
+	if err := store.Record(&ScoreResult{Provider: IMDB, ID: "tt0371746"}); err != nil {
+		t.Errorf("Recording to a disabled history failed: %s", err.Error())
+	}
+	if _, err := store.Diff(AllProviders, 10); err == nil {
+		t.Errorf("Error was expected for a disabled history")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,7 +21,12 @@ const imdbAPIBaseURL = "https://v2.sg.media-imdb.com/suggests/"
 
 type (
 	// IMDb represents an IMDB provider
-	IMDb struct{}
+	IMDb struct {
+		fetcher Fetcher
+		// FetchRatings also retrieves the ratings page in Score to get the
+		// number of votes per rating
+		FetchRatings bool
+	}
 
 	imdbSearchResult struct {
 		V     int              `json:"v"`
@@ -46,6 +52,45 @@ type (
 		Subline string        `json:"s"`
 	}
 
+	// imdbTitleRating is the rating found in a title page by any parser
+	imdbTitleRating struct {
+		Kind      string
+		Value     float32
+		Votes     uint
+		Metascore uint
+	}
+
+	imdbLinkedData struct {
+		Type            linkedStrings `json:"@type"`
+		AggregateRating *struct {
+			RatingValue jsonNumber `json:"ratingValue"`
+			RatingCount jsonNumber `json:"ratingCount"`
+		} `json:"aggregateRating"`
+	}
+
+	imdbNextData struct {
+		Props struct {
+			PageProps struct {
+				AboveTheFoldData struct {
+					TitleType struct {
+						ID string `json:"id"`
+					} `json:"titleType"`
+					RatingsSummary struct {
+						AggregateRating float32 `json:"aggregateRating"`
+						VoteCount       uint    `json:"voteCount"`
+					} `json:"ratingsSummary"`
+					Metacritic struct {
+						Metascore struct {
+							Score uint `json:"score"`
+						} `json:"metascore"`
+					} `json:"metacritic"`
+				} `json:"aboveTheFoldData"`
+			} `json:"pageProps"`
+		} `json:"props"`
+	}
+
+	jsonNumber float64
+
 	imdbSearchOutputFormat struct {
 		ID     string `json:"id"`
 		Name   string `json:"name"`
@@ -53,42 +98,62 @@ type (
 	}
 )
 
-// NewIMDb creates a new instance of IMDb provider
-func NewIMDb() *IMDb {
-	return &IMDb{}
+// NewIMDb creates a new instance of IMDb provider that uses the given fetcher
+// to retrieve pages. A nil fetcher means DefaultFetcher.
+func NewIMDb(fetcher Fetcher) *IMDb {
+	if fetcher == nil {
+		fetcher = DefaultFetcher
+	}
+	return &IMDb{fetcher: fetcher}
 }
 
-// Search returns movies for a given query from IMDB suggests API
-func (imdb *IMDb) Search(query string) ([]SearchResult, error) {
+// Search returns movies for a given query from IMDB suggests API. Returns the
+// first page of results.
+func (imdb *IMDb) Search(ctx context.Context, query string) ([]SearchResult, error) {
 	if query == "" {
 		return nil, nil
 	}
 
-	fullURL := imdbAPIBaseURL + string(query[0]) + "/"
+	page, err := imdb.SearchPage(ctx, query, defaultPage())
+	if err != nil {
+		return nil, err
+	}
+	return page.Results, nil
+}
+
+// SearchPage returns the given page of titles for a query. IMDB suggests API
+// has a fixed number of results and no total, so they are paged locally.
+func (imdb *IMDb) SearchPage(ctx context.Context, query string, page PageRequest) (*SearchPage, error) {
+	if query == "" {
+		return nil, errors.New("query is empty")
+	}
 
-	query = strings.Replace(query, " ", "_", -1)
-	fullURL += query + ".json"
+	fullURL := imdbSuggestURL(query)
+	if fullURL == "" {
+		return nil, fmt.Errorf("query '%s' has no letters or numbers", query)
+	}
 
-	body, err := Get(fullURL)
+	response, err := imdb.fetcher.Get(ctx, fullURL)
 	if err != nil {
 		return nil, err
 	}
 
-	str := string(body)
+	str := string(response.Body)
 	start := strings.Index(str, "(") + 1
 	end := strings.LastIndex(str, ")")
 	if start > 0 && end > start {
 		str = str[start:end]
 	} else {
-		return nil, errors.New("couldn't find string between `(` `)` tokens")
+		return nil, parseError(fullURL, errors.New("couldn't find string between `(` `)` tokens"))
 	}
 
 	var result imdbSearchResult
 	err = json.Unmarshal([]byte(str), &result)
 	if err != nil {
-		return nil, err
+		return nil, parseError(fullURL, err)
 	}
 
+	meta := response.Meta()
 	r := make([]SearchResult, 0)
 	for _, item := range result.Data {
 		sr := SearchResult{
@@ -96,6 +161,8 @@ func (imdb *IMDb) Search(query string) ([]SearchResult, error) {
 			ID:       item.ID,
 			Title:    item.Label,
 			Year:     item.Year,
+			Kind:     imdbKind(item.Q),
+			Meta:     meta,
 		}
 
 		if len(item.Image) > 0 {
@@ -105,44 +172,218 @@ func (imdb *IMDb) Search(query string) ([]SearchResult, error) {
 		r = append(r, sr)
 	}
 
-	return r, nil
+	rankResults(r, query, page.Year)
+	return paginate(r, page, 0), nil
 }
 
 // Score gets the score for the given imdb id
-func (imdb *IMDb) Score(id string) (*ScoreResult, error) {
+func (imdb *IMDb) Score(ctx context.Context, id string) (*ScoreResult, error) {
 	if id == "" {
 		return nil, errors.New("id is empty")
 	}
 
 	fullURL := imdbBaseURL + "title/" + id
-	body, err := Get(fullURL)
+	response, err := imdb.fetcher.Get(ctx, fullURL)
 	if err != nil {
 		return nil, err
 	}
 
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response.Body))
+	if err != nil {
+		return nil, parseError(fullURL, err)
+	}
+
+	rating := parseTitleRating(doc)
+	if rating.Value == 0 {
+		return nil, parseError(fullURL, fmt.Errorf("couldn't find score for movie %s in JSON-LD, __NEXT_DATA__ or page markup", id))
+	}
+
+	result := &ScoreResult{
+		ID:              id,
+		Provider:        IMDB,
+		Kind:            rating.Kind,
+		Score:           rating.Value,
+		NormalizedScore: normalizeScore(IMDB, rating.Value),
+		Votes:           rating.Votes,
+		Metascore:       rating.Metascore,
+		Meta:            response.Meta(),
+	}
+
+	if imdb.FetchRatings {
+		// The score is still valid without the distribution
+		result.Distribution, err = imdb.distribution(ctx, fullURL+"/ratings")
+		if err != nil {
+			DefaultLogger.Error("ratings fetch failed", "id", id, "error", err)
+		}
+	}
+
+	return result, nil
+}
+
+// imdbTitleParsers extract the rating of a title page, from the most to the
+// least reliable. Structured data survives redesigns while CSS paths don't.
+var imdbTitleParsers = []func(*goquery.Document) imdbTitleRating{
+	parseTitleLD,
+	parseTitleNextData,
+	parseTitleCSS,
+}
+
+// parseTitleRating runs every parser and fills each field with the first
+// value found, so a parser may complete what previous ones missed
+func parseTitleRating(doc *goquery.Document) imdbTitleRating {
+	var result imdbTitleRating
+	for _, parse := range imdbTitleParsers {
+		r := parse(doc)
+		if result.Kind == "" {
+			result.Kind = r.Kind
+		}
+		if result.Value == 0 {
+			result.Value = r.Value
+		}
+		if result.Votes == 0 {
+			result.Votes = r.Votes
+		}
+		if result.Metascore == 0 {
+			result.Metascore = r.Metascore
+		}
+	}
+	return result
+}
+
+// parseTitleLD reads the schema.org Movie in application/ld+json scripts
+func parseTitleLD(doc *goquery.Document) imdbTitleRating {
+	var result imdbTitleRating
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		var data imdbLinkedData
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil || data.AggregateRating == nil {
+			return true
+		}
+
+		result.Kind = linkedKind(data.Type)
+		result.Value = float32(data.AggregateRating.RatingValue)
+		result.Votes = uint(data.AggregateRating.RatingCount)
+		return false
+	})
+	return result
+}
+
+// parseTitleNextData reads the page props embedded by Next.js in the
+// redesigned title page
+func parseTitleNextData(doc *goquery.Document) imdbTitleRating {
+	var result imdbTitleRating
+
+	text := doc.Find("script#__NEXT_DATA__").Text()
+	if text == "" {
+		return result
+	}
+
+	var data imdbNextData
+	if err := json.Unmarshal([]byte(text), &data); err != nil {
+		return result
+	}
+
+	title := data.Props.PageProps.AboveTheFoldData
+	result.Kind = imdbKind(title.TitleType.ID)
+	result.Value = title.RatingsSummary.AggregateRating
+	result.Votes = title.RatingsSummary.VoteCount
+	result.Metascore = title.Metacritic.Metascore.Score
+	return result
+}
+
+// parseTitleCSS reads the rating from the page markup using the selectors of
+// DefaultSelectors
+func parseTitleCSS(doc *goquery.Document) imdbTitleRating {
+	var result imdbTitleRating
+
+	scoreText := DefaultSelectors.Extract(doc, IMDB, "rating")
+	if number, err := strconv.ParseFloat(scoreText, 32); err == nil {
+		result.Value = float32(number)
+	}
+
+	result.Votes = scoreAsInt(DefaultSelectors.Extract(doc, IMDB, "votes"))
+	result.Metascore = scoreAsInt(DefaultSelectors.Extract(doc, IMDB, "metascore"))
+	return result
+}
+
+// UnmarshalJSON accepts numbers either as JSON numbers or strings, IMDb has
+// used both in its JSON-LD
+func (n *jsonNumber) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		*n = 0
+		return nil
+	}
+
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return err
+	}
+	*n = jsonNumber(number)
+	return nil
+}
+
+// Details gets the metadata of the given imdb id from its title page
+func (imdb *IMDb) Details(ctx context.Context, id string) (*DetailsResult, error) {
+	if id == "" {
+		return nil, errors.New("id is empty")
+	}
+
+	fullURL := imdbBaseURL + "title/" + id
+	response, err := imdb.fetcher.Get(ctx, fullURL)
 	if err != nil {
 		return nil, err
 	}
 
-	container := doc.Find("#title-overview-widget")
-	scoreText := container.Find("div.ratings_wrapper > div.imdbRating > div.ratingValue > strong > span").Text()
-	scoreText = strings.TrimSpace(scoreText)
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response.Body))
+	if err != nil {
+		return nil, parseError(fullURL, err)
+	}
 
-	if scoreText == "" {
-		return nil, fmt.Errorf("Couldn't find score for movie %s", id)
+	result := &DetailsResult{Provider: IMDB, ID: id}
+	if movie := findLinkedMovie(doc); movie != nil {
+		result = movie.details(IMDB, id)
 	}
+	result.complete(doc, IMDB)
+
+	if result.Title == "" {
+		return nil, parseError(fullURL, fmt.Errorf("couldn't find details for movie %s", id))
+	}
+
+	result.Meta = response.Meta()
+	return result, nil
+}
 
-	number, err := strconv.ParseFloat(scoreText, 32)
+// distribution returns the number of votes per rating listed in the given
+// ratings page, from the highest rating to the lowest
+func (imdb *IMDb) distribution(ctx context.Context, fullURL string) ([]RatingVotes, error) {
+	response, err := imdb.fetcher.Get(ctx, fullURL)
 	if err != nil {
 		return nil, err
 	}
 
-	result := &ScoreResult{
-		ID:       id,
-		Provider: IMDB,
-		Score:    float32(number),
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response.Body))
+	if err != nil {
+		return nil, parseError(fullURL, err)
 	}
+
+	result := make([]RatingVotes, 0, 10)
+	DefaultSelectors.Find(doc, IMDB, "ratings_rows").Each(func(i int, row *goquery.Selection) {
+		// Heading row doesn't have a rating
+		rating := scoreAsInt(DefaultSelectors.ExtractFrom(row, IMDB, "ratings_row_rating"))
+		if rating == 0 {
+			return
+		}
+
+		result = append(result, RatingVotes{
+			Rating: rating,
+			Votes:  scoreAsInt(DefaultSelectors.ExtractFrom(row, IMDB, "ratings_row_votes")),
+		})
+	})
+
+	if len(result) == 0 {
+		return nil, parseError(fullURL, errors.New("couldn't find rating distribution"))
+	}
+
 	return result, nil
 }
 
@@ -164,6 +405,18 @@ func (searchResult *imdbSearchResult) toOutputFormat() []imdbSearchOutputFormat
 	return result
 }
 
+// imdbSuggestURL returns the URL of the suggestions for the given query, e.g.
+// https://v2.sg.media-imdb.com/suggests/a/amelie.json for "Amélie". Suggests
+// API files are lowercase without accents and grouped by first letter. An
+// empty string means the query has no letters or numbers.
+func imdbSuggestURL(query string) string {
+	key := strings.Replace(foldTitle(query), " ", "_", -1)
+	if key == "" {
+		return ""
+	}
+	return imdbAPIBaseURL + string([]rune(key)[0]) + "/" + key + ".json"
+}
+
 func getString(val interface{}) string {
 	typ := reflect.TypeOf(val)
 	if typ != nil && typ.Kind() == reflect.String {
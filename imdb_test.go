@@ -1,15 +1,22 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
 
 func TestImdbSearch(t *testing.T) {
-	imdb := NewIMDb()
+	imdb := NewIMDb(testFetcher)
 
 	query := "iron man 2008"
 
-	result, err := imdb.Search(query)
+	result, err := imdb.Search(context.Background(), query)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
 	expectedFirstItem := &imdbSearchItem{
@@ -20,7 +27,7 @@ func TestImdbSearch(t *testing.T) {
 	}
 
 	if len(result) == 0 {
-		t.Errorf("Size was incorrect, got: 0, expected > 0")
+		t.Fatalf("Size was incorrect, got: 0, expected > 0")
 	}
 
 	if !isSearchItemEqual(result[0], *expectedFirstItem) {
@@ -28,6 +35,177 @@ func TestImdbSearch(t *testing.T) {
 	}
 }
 
+func TestImdbScore(t *testing.T) {
+	imdb := NewIMDb(testFetcher)
+
+	result, err := imdb.Score(context.Background(), "tt0371746")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedScore := float32(7.9)
+	if result.Score != expectedScore {
+		t.Errorf("Score was incorrect, got %f, expected: %f", result.Score, expectedScore)
+	}
+}
+
+func TestImdbScoreVotes(t *testing.T) {
+	imdb := NewIMDb(testFetcher)
+
+	result, err := imdb.Score(context.Background(), "tt0371746")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Votes != 881325 {
+		t.Errorf("Votes were incorrect, got %d, expected: %d", result.Votes, 881325)
+	}
+	if result.Metascore != 79 {
+		t.Errorf("Metascore was incorrect, got %d, expected: %d", result.Metascore, 79)
+	}
+	if result.Distribution != nil {
+		t.Errorf("Distribution was fetched without FetchRatings")
+	}
+}
+
+func TestImdbScoreDistribution(t *testing.T) {
+	imdb := NewIMDb(testFetcher)
+	imdb.FetchRatings = true
+
+	result, err := imdb.Score(context.Background(), "tt0371746")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Distribution) != 10 {
+		t.Fatalf("Distribution size was incorrect, got %d, expected: %d", len(result.Distribution), 10)
+	}
+
+	first := result.Distribution[0]
+	if first.Rating != 10 || first.Votes != 174512 {
+		t.Errorf("First rating was incorrect, got %d with %d votes, expected: 10 with 174512 votes", first.Rating, first.Votes)
+	}
+
+	var total uint
+	for _, r := range result.Distribution {
+		total += r.Votes
+	}
+	if total != result.Votes {
+		t.Errorf("Distribution total was incorrect, got %d, expected: %d", total, result.Votes)
+	}
+}
+
+func TestImdbScoreRedesign(t *testing.T) {
+	imdb := NewIMDb(testFetcher)
+
+	result, err := imdb.Score(context.Background(), "tt1228705")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Score != 6.9 {
+		t.Errorf("Score was incorrect, got %f, expected: %f", result.Score, 6.9)
+	}
+	if result.Votes != 850123 {
+		t.Errorf("Votes were incorrect, got %d, expected: %d", result.Votes, 850123)
+	}
+	if result.Metascore != 57 {
+		t.Errorf("Metascore was incorrect, got %d, expected: %d", result.Metascore, 57)
+	}
+}
+
+func TestImdbScoreNextData(t *testing.T) {
+	imdb := NewIMDb(stubFetcher{
+		imdbBaseURL + "title/tt0000002": `<html><body><script id="__NEXT_DATA__" type="application/json">
+			{"props":{"pageProps":{"aboveTheFoldData":{"ratingsSummary":{"aggregateRating":8.1,"voteCount":1200}}}}}
+		</script></body></html>`,
+	})
+
+	result, err := imdb.Score(context.Background(), "tt0000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Score != 8.1 || result.Votes != 1200 {
+		t.Errorf("Rating was incorrect, got %f with %d votes, expected: 8.1 with 1200 votes", result.Score, result.Votes)
+	}
+}
+
+func TestImdbScoreCSSFallback(t *testing.T) {
+	response, err := testFetcher.Get(context.Background(), imdbBaseURL+"title/tt0371746")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rating := parseTitleCSS(doc)
+	if rating.Value != 7.9 || rating.Votes != 881325 || rating.Metascore != 79 {
+		t.Errorf("Rating was incorrect, got %+v, expected: 7.9 with 881325 votes and metascore 79", rating)
+	}
+}
+
+func TestImdbSearchKind(t *testing.T) {
+	result, err := NewIMDb(testFetcher).Search(context.Background(), "iron man")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := make(map[string]string)
+	for _, r := range result {
+		kinds[r.Title] = r.Kind
+	}
+
+	if kinds["Iron Man"] != KindMovie {
+		t.Errorf("Kind of Iron Man was incorrect, got: %s, expected: %s", kinds["Iron Man"], KindMovie)
+	}
+	if kinds["Iron Man: Armored Adventures"] != KindTV {
+		t.Errorf("Kind of Iron Man: Armored Adventures was incorrect, got: %s, expected: %s", kinds["Iron Man: Armored Adventures"], KindTV)
+	}
+}
+
+func TestImdbKind(t *testing.T) {
+	cases := map[string]string{
+		"feature":        KindMovie,
+		"TV movie":       KindMovie,
+		"tvMovie":        KindMovie,
+		"video":          KindMovie,
+		"short":          KindMovie,
+		"TV series":      KindTV,
+		"tvMiniSeries":   KindTV,
+		"video game":     "",
+		"podcast series": "",
+		"music video":    "",
+		"":               "",
+	}
+
+	for titleType, expected := range cases {
+		if kind := imdbKind(titleType); kind != expected {
+			t.Errorf("Kind of %q was incorrect, got: %s, expected: %s", titleType, kind, expected)
+		}
+	}
+}
+
 func isSearchItemEqual(a SearchResult, b imdbSearchItem) bool {
 	return a.ID == b.ID
 }
+
+func TestImdbScoreDistributionFailure(t *testing.T) {
+	page, err := ioutil.ReadFile("testdata/www.imdb.com_title_tt0371746")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ratings page is missing
+	imdb := NewIMDb(stubFetcher{imdbBaseURL + "title/tt0371746": string(page)})
+	imdb.FetchRatings = true
+
+	result, err := imdb.Score(context.Background(), "tt0371746")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score != 7.9 || result.Distribution != nil {
+		t.Errorf("Result was incorrect, got: %+v", result)
+	}
+}
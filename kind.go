@@ -0,0 +1,56 @@
+package main
+
+import "strings"
+
+// Kinds of titles returned by providers. KindAny is only used to filter.
+const (
+	KindMovie = "movie"
+	KindTV    = "tv"
+	KindAny   = "any"
+)
+
+var supportedKinds = []string{KindMovie, KindTV, KindAny}
+
+// imdbMovieTypes are the IMDb title types of films, as named by search
+// suggestions and by title pages
+var imdbMovieTypes = []string{"feature", "movie", "tv movie", "tvmovie", "video", "short"}
+
+// filterKind returns the results of the given kind. KindAny keeps movies and
+// TV titles, but not people, games and other results without a kind.
+func filterKind(results []SearchResult, kind string) []SearchResult {
+	filtered := make([]SearchResult, 0, len(results))
+	for _, r := range results {
+		if r.Kind == kind || ((kind == KindAny || kind == "") && r.Kind != "") {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// linkedKind returns the kind of a schema.org type, e.g. TVSeries, or an
+// empty string if it isn't a title
+func linkedKind(types []string) string {
+	for _, t := range types {
+		switch t {
+		case "Movie":
+			return KindMovie
+		case "TVSeries", "TVSeason", "TVEpisode":
+			return KindTV
+		}
+	}
+	return ""
+}
+
+// imdbKind returns the kind of an IMDb title type, e.g. "TV series" or
+// "feature", or an empty string if it isn't a film or a TV title, e.g.
+// "video game" or "podcast series"
+func imdbKind(titleType string) string {
+	titleType = strings.ToLower(titleType)
+	switch {
+	case isArgValid(titleType, imdbMovieTypes):
+		return KindMovie
+	case strings.HasPrefix(titleType, "tv"):
+		return KindTV
+	}
+	return ""
+}
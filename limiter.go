@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// HostLimiter limits how fast requests are sent to each host. Every host
+	// has its own token bucket, so a slow provider doesn't slow down others.
+	HostLimiter struct {
+		mu      sync.Mutex
+		rates   map[string]float64
+		buckets map[string]*tokenBucket
+	}
+
+	tokenBucket struct {
+		rate   float64
+		burst  float64
+		tokens float64
+		last   time.Time
+	}
+)
+
+// DefaultProviderRates is the maximum number of requests per second sent to
+// the hosts of each provider
+var DefaultProviderRates = map[string]float64{
+	IMDB:    2,
+	RottenT: 1,
+}
+
+// NewHostLimiter creates a new instance of HostLimiter using the given
+// requests per second for the hosts of each provider. Hosts without a rate
+// aren't limited.
+func NewHostLimiter(rates map[string]float64) *HostLimiter {
+	l := &HostLimiter{
+		rates:   make(map[string]float64),
+		buckets: make(map[string]*tokenBucket),
+	}
+	for provider, rate := range rates {
+		for _, host := range providerHosts(provider) {
+			l.SetRate(host, rate)
+		}
+	}
+	return l
+}
+
+// SetRate sets the maximum number of requests per second sent to the given
+// host. Zero or less means no limit.
+func (l *HostLimiter) SetRate(host string, rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rates[host] = rate
+	delete(l.buckets, host)
+}
+
+// Wait blocks until a request can be sent to the given host or the context is
+// done
+func (l *HostLimiter) Wait(ctx context.Context, host string) error {
+	wait := l.reserve(host)
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// reserve takes a token from the bucket of the host and returns how long to
+// wait until that token is available
+func (l *HostLimiter) reserve(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rate := l.rates[host]
+	if rate <= 0 {
+		return 0
+	}
+
+	bucket, ok := l.buckets[host]
+	if !ok {
+		// Allow a burst of one second worth of requests, at least one
+		burst := rate
+		if burst < 1 {
+			burst = 1
+		}
+		bucket = &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+		l.buckets[host] = bucket
+	}
+
+	return bucket.take(time.Now())
+}
+
+// take removes a token from the bucket, which may leave the bucket in debt,
+// and returns how long until the bucket is out of it
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// providerHosts returns the hosts requested by the given provider
+func providerHosts(provider string) []string {
+	var urls []string
+	switch provider {
+	case IMDB:
+		urls = []string{imdbBaseURL, imdbAPIBaseURL}
+	case RottenT:
+		urls = []string{rottenBaseURL}
+	}
+
+	hosts := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if parsed, err := url.Parse(u); err == nil {
+			hosts = append(hosts, parsed.Host)
+		}
+	}
+	return hosts
+}
+
+// parseRates parses a list of requests per second per provider separated by
+// commas, e.g. imdb=2,rotten=0.5
+func parseRates(value string) (map[string]float64, error) {
+	return parsePairs(value, "rate", "provider=requests per second", supportedProviders, func(text string) (float64, error) {
+		return strconv.ParseFloat(text, 64)
+	})
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	l := NewHostLimiter(nil)
+	l.SetRate("www.imdb.com", 50)
+
+	start := time.Now()
+	// A burst of 50 requests is allowed right away, the next 5 must wait
+	for i := 0; i < 55; i++ {
+		if err := l.Wait(context.Background(), "www.imdb.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("Requests were not limited, took: %s", elapsed)
+	}
+
+	// Hosts without a rate are not limited
+	start = time.Now()
+	for i := 0; i < 100; i++ {
+		l.Wait(context.Background(), "www.rottentomatoes.com")
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Requests were limited, took: %s", elapsed)
+	}
+}
+
+func TestHostLimiterCancel(t *testing.T) {
+	l := NewHostLimiter(map[string]float64{RottenT: 0.1})
+
+	l.Wait(context.Background(), "www.rottentomatoes.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx, "www.rottentomatoes.com"); err == nil {
+		t.Errorf("Error was expected when context is done")
+	}
+}
+
+func TestRobotsRules(t *testing.T) {
+	rules := parseRobots([]byte(`
+# Rules for a specific crawler don't apply
+User-agent: Googlebot
+Disallow: /
+
+User-agent: Bingbot
+User-agent: *
+Disallow: /search
+Disallow: /*/ratings$
+Allow: /search/title
+`))
+
+	cases := map[string]bool{
+		"/title/tt0371746":          true,
+		"/search?q=iron":            false,
+		"/search/title?genres=a":    true,
+		"/title/tt0371746/ratings":  false,
+		"/title/tt0371746/ratings/": true,
+	}
+
+	for path, expected := range cases {
+		if allowed := rules.allowed(path); allowed != expected {
+			t.Errorf("Path %s was incorrect, got: %t, expected: %t", path, allowed, expected)
+		}
+	}
+}
+
+func TestHTTPFetcherRobots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	f := newTestHTTPFetcher(0)
+	f.CheckRobots = true
+
+	if _, err := f.Get(context.Background(), server.URL+"/m/iron_man"); err != nil {
+		t.Errorf("Allowed path failed: %s", err.Error())
+	}
+
+	_, err := f.Get(context.Background(), server.URL+"/private/page")
+	if !errors.Is(err, ErrBlocked) {
+		t.Errorf("Error was incorrect, got: %v, expected: %v", err, ErrBlocked)
+	}
+}
+
+func TestHTTPFetcherRobotsUnreachable(t *testing.T) {
+	var robotsStatus, robotsFetches int32 = http.StatusServiceUnavailable, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			atomic.AddInt32(&robotsFetches, 1)
+			w.WriteHeader(int(atomic.LoadInt32(&robotsStatus)))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	f := newTestHTTPFetcher(0)
+	f.CheckRobots = true
+
+	// A server error disallows everything and is fetched again next time
+	_, err := f.Get(context.Background(), server.URL+"/m/iron_man")
+	if !errors.Is(err, ErrBlocked) {
+		t.Errorf("Error was incorrect, got: %v, expected: %v", err, ErrBlocked)
+	}
+
+	// A missing robots.txt allows everything and is kept
+	atomic.StoreInt32(&robotsStatus, http.StatusNotFound)
+	for i := 0; i < 2; i++ {
+		if _, err := f.Get(context.Background(), server.URL+"/m/iron_man"); err != nil {
+			t.Errorf("Allowed path failed: %s", err.Error())
+		}
+	}
+
+	if fetches := atomic.LoadInt32(&robotsFetches); fetches != 2 {
+		t.Errorf("Robots fetches were incorrect, got: %d, expected: 2", fetches)
+	}
+}
+
+func TestHTTPFetcherRobotsTimeout(t *testing.T) {
+	var stall int32 = 1
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" && atomic.LoadInt32(&stall) == 1 {
+			<-release
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	f := newTestHTTPFetcher(0)
+	f.CheckRobots = true
+
+	// Running out of time isn't the robots.txt disallowing the request
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := f.Get(ctx, server.URL+"/m/iron_man")
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Error was incorrect, got: %v, expected: %v", err, ErrTimeout)
+	}
+
+	// Nor is it kept for later requests
+	atomic.StoreInt32(&stall, 0)
+	if _, err := f.Get(context.Background(), server.URL+"/m/iron_man"); err != nil {
+		t.Errorf("Request after the timeout failed: %s", err.Error())
+	}
+}
+
+func TestHTTPFetcherRobotsPerOrigin(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			<-release
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer slow.Close()
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer fast.Close()
+
+	f := newTestHTTPFetcher(0)
+	f.CheckRobots = true
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.Get(context.Background(), slow.URL+"/m/iron_man")
+	}()
+	// The blocked request must finish before the test returns, so it doesn't
+	// log while another test replaces the logger
+	defer func() {
+		close(release)
+		<-done
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// The slow robots.txt of another origin must not hold this request
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := f.Get(ctx, fast.URL+"/m/iron_man"); err != nil {
+		t.Errorf("Request to another origin failed: %s", err.Error())
+	}
+}
+
+func TestProviderHosts(t *testing.T) {
+	hosts := providerHosts(IMDB)
+	if len(hosts) != 2 {
+		t.Fatalf("Size was incorrect, got: %d, expected: 2", len(hosts))
+	}
+
+	u, _ := url.Parse(imdbBaseURL + "title/tt0371746")
+	if hosts[0] != u.Host {
+		t.Errorf("Host was incorrect, got: %s, expected: %s", hosts[0], u.Host)
+	}
+}
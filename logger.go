@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level represents how much a Logger reports, every level includes the ones
+// before it
+type Level int
+
+const (
+	// LevelError only reports failures
+	LevelError Level = iota
+	// LevelInfo also reports what the application did, e.g. the output file
+	LevelInfo
+	// LevelDebug also reports every request along with retry and cache
+	// decisions
+	LevelDebug
+	// LevelTrace also dumps response bodies to the dump directory
+	LevelTrace
+)
+
+// LogFormatText and LogFormatJSON are the supported formats of log lines
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+var supportedLogFormats = []string{LogFormatText, LogFormatJSON}
+
+var levelNames = map[Level]string{
+	LevelError: "error",
+	LevelInfo:  "info",
+	LevelDebug: "debug",
+	LevelTrace: "trace",
+}
+
+type (
+	// Logger writes leveled log lines made of a message and key-value pairs,
+	// e.g. Debug("fetched", "url", url, "status", 200)
+	Logger struct {
+		Out    io.Writer
+		Level  Level
+		Format string
+		// DumpDir is where response bodies are dumped at trace level. Nothing
+		// is dumped when it's empty.
+		DumpDir string
+
+		mu sync.Mutex
+	}
+
+	// verbosity is the value of -v flag. It's a boolean flag that counts how
+	// many times it's given, so -v -v means more than -v.
+	verbosity int
+)
+
+// DefaultLogger is the logger used by fetchers, it writes to standard error
+var DefaultLogger = NewLogger(os.Stderr, LevelInfo, LogFormatText)
+
+// NewLogger creates a new instance of Logger
+func NewLogger(out io.Writer, level Level, format string) *Logger {
+	return &Logger{Out: out, Level: level, Format: format}
+}
+
+// Enabled reports whether lines of the given level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level <= l.Level
+}
+
+// Error logs a failure
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.Log(LevelError, msg, keyvals...)
+}
+
+// Info logs what the application did
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.Log(LevelInfo, msg, keyvals...)
+}
+
+// Debug logs details useful to understand what the application did
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.Log(LevelDebug, msg, keyvals...)
+}
+
+// Log writes a line with the given message and key-value pairs if its level
+// is enabled
+func (l *Logger) Log(level Level, msg string, keyvals ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "")
+	}
+
+	var line bytes.Buffer
+	if l.Format == LogFormatJSON {
+		l.writeJSON(&line, level, msg, keyvals)
+	} else {
+		l.writeText(&line, level, msg, keyvals)
+	}
+	line.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Out.Write(line.Bytes())
+}
+
+// Dump writes the body of the response of the given URL to the dump directory
+// at trace level. Dumps are named like fixtures, so the directory can be
+// replayed with -fixtures. Failed responses have their status appended to the
+// name, e.g. www.imdb.com_title_tt0371746_503, so they're never replayed.
+func (l *Logger) Dump(url string, status int, body []byte) {
+	if !l.Enabled(LevelTrace) || l.DumpDir == "" {
+		return
+	}
+
+	name := fixtureName(url)
+	if status != http.StatusOK {
+		name += "_" + strconv.Itoa(status)
+	}
+	filename := filepath.Join(l.DumpDir, name)
+	err := os.MkdirAll(l.DumpDir, 0755)
+	if err == nil {
+		err = ioutil.WriteFile(filename, body, outputPerm)
+	}
+	if err != nil {
+		l.Error("dump failed", "url", url, "error", err)
+		return
+	}
+	l.Log(LevelTrace, "dumped", "url", url, "status", status, "file", filename, "bytes", len(body))
+}
+
+func (l *Logger) writeText(w *bytes.Buffer, level Level, msg string, keyvals []interface{}) {
+	fmt.Fprintf(w, "%s %-5s %s", time.Now().Format(time.RFC3339), levelNames[level], msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		value := logValue(keyvals[i+1])
+		if s, ok := value.(string); ok && (s == "" || strings.ContainsAny(s, " \"=")) {
+			value = strconv.Quote(s)
+		}
+		fmt.Fprintf(w, " %v=%v", keyvals[i], value)
+	}
+}
+
+func (l *Logger) writeJSON(w *bytes.Buffer, level Level, msg string, keyvals []interface{}) {
+	// Fields are written one by one to keep the order they were given
+	field := func(key string, value interface{}) {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			encoded, _ = json.Marshal(fmt.Sprint(value))
+		}
+		k, _ := json.Marshal(key)
+		w.WriteByte(',')
+		w.Write(k)
+		w.WriteByte(':')
+		w.Write(encoded)
+	}
+
+	fmt.Fprintf(w, `{"time":%q`, time.Now().Format(time.RFC3339))
+	field("level", levelNames[level])
+	field("msg", msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		field(fmt.Sprint(keyvals[i]), logValue(keyvals[i+1]))
+	}
+	w.WriteByte('}')
+}
+
+// logValue converts values that don't encode well as they are, e.g. errors
+// and durations, to strings
+func logValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+// logLevel returns the level set by -v and -q flags
+func logLevel(v verbosity, quiet bool) Level {
+	if quiet {
+		return LevelError
+	}
+	level := LevelInfo + Level(v)
+	if level > LevelTrace {
+		level = LevelTrace
+	}
+	return level
+}
+
+func (v *verbosity) String() string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*v))
+}
+
+// Set is called with "true" every time the flag is given without value,
+// otherwise the value is the verbosity itself, e.g. -v=2
+func (v *verbosity) Set(value string) error {
+	switch value {
+	case "true":
+		*v++
+		return nil
+	case "false":
+		*v = 0
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid verbosity '%s'", value)
+	}
+	*v = verbosity(n)
+	return nil
+}
+
+func (v *verbosity) IsBoolFlag() bool {
+	return true
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerLevels(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewLogger(&out, LevelInfo, LogFormatText)
+
+	logger.Debug("hidden")
+	logger.Info("outputted", "file", "result file.json", "bytes", 42)
+
+	line := out.String()
+	if strings.Contains(line, "hidden") {
+		t.Errorf("Debug line was written at info level: %s", line)
+	}
+	if !strings.Contains(line, ` info  outputted file="result file.json" bytes=42`) {
+		t.Errorf("Line was incorrect, got: %s", line)
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewLogger(&out, LevelDebug, LogFormatJSON)
+
+	logger.Debug("request", "url", "https://www.imdb.com/title/tt0371746", "status", 200, "latency", 1500*time.Millisecond, "error", errors.New("boom"))
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("Line is not JSON: %s", out.String())
+	}
+	if line["level"] != "debug" || line["msg"] != "request" || line["status"] != float64(200) || line["latency"] != "1.5s" || line["error"] != "boom" {
+		t.Errorf("Line was incorrect, got: %s", out.String())
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	cases := []struct {
+		verbose  verbosity
+		quiet    bool
+		expected Level
+	}{
+		{0, false, LevelInfo},
+		{1, false, LevelDebug},
+		{2, false, LevelTrace},
+		{5, false, LevelTrace},
+		{2, true, LevelError},
+	}
+
+	for _, c := range cases {
+		if level := logLevel(c.verbose, c.quiet); level != c.expected {
+			t.Errorf("Level of -v %d -quiet %v was incorrect, got: %d, expected: %d", c.verbose, c.quiet, level, c.expected)
+		}
+	}
+}
+
+func TestHTTPFetcherLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>body</html>"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var out bytes.Buffer
+	defer func(logger *Logger) { DefaultLogger = logger }(DefaultLogger)
+
+	for _, level := range []Level{LevelDebug, LevelTrace} {
+		out.Reset()
+		DefaultLogger = NewLogger(&out, level, LogFormatText)
+		DefaultLogger.DumpDir = dir
+
+		if _, err := NewHTTPFetcher().Get(context.Background(), server.URL); err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(out.String(), "status=200") || !strings.Contains(out.String(), "bytes=17") {
+			t.Errorf("Request was not logged, got: %s", out.String())
+		}
+		if strings.Contains(out.String(), "<html>") {
+			t.Errorf("Body was logged, got: %s", out.String())
+		}
+
+		_, err := os.Stat(filepath.Join(dir, fixtureName(server.URL)))
+		if dumped := err == nil; dumped != (level == LevelTrace) {
+			t.Errorf("Body dump at level %d was incorrect, got: %v", level, dumped)
+		}
+	}
+}
+
+func TestHTTPFetcherDumpsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("<html>blocked</html>"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(logger *Logger) { DefaultLogger = logger }(DefaultLogger)
+	DefaultLogger = NewLogger(ioutil.Discard, LevelTrace, LogFormatText)
+	DefaultLogger.DumpDir = dir
+
+	if _, err := newTestHTTPFetcher(0).Get(context.Background(), server.URL); err == nil {
+		t.Fatal("Error was expected")
+	}
+
+	// Failed responses are kept apart from the ones that can be replayed
+	body, err := ioutil.ReadFile(filepath.Join(dir, fixtureName(server.URL)+"_503"))
+	if err != nil || string(body) != "<html>blocked</html>" {
+		t.Errorf("Body dump was incorrect, got: %q, %v", body, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, fixtureName(server.URL))); err == nil {
+		t.Errorf("Failed response was dumped as a fixture")
+	}
+}
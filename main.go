@@ -1,57 +1,180 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"math/rand"
-	"net/http"
+	"os"
+	"os/signal"
+	"strings"
 	"time"
 )
 
 var opScore = "score"
 var opSearch = "search"
-
-var supportedOperations = []string{opScore, opSearch}
+var opMatch = "match"
+var opServe = "serve"
+var opBatch = "batch"
+var opSelftest = "selftest"
+var opDetails = "details"
+var opPeople = "people"
+var opFranchise = "franchise"
+var opAggregate = "aggregate"
+var opHistory = "history"
+var opDiff = "diff"
+
+var supportedOperations = []string{opScore, opSearch, opMatch, opServe, opBatch, opSelftest, opDetails, opPeople, opFranchise, opAggregate, opHistory, opDiff}
+
+// requestOperations are the operations run by a single request, e.g. a line
+// of a batch operation
+var requestOperations = []string{opSearch, opScore, opDetails, opMatch, opSelftest, opPeople, opFranchise, opAggregate, opHistory, opDiff}
+
+// idOperations are the operations that require an id instead of a query
+var idOperations = []string{opScore, opDetails, opFranchise, opAggregate, opHistory}
 var supportedProviders = []string{IMDB, RottenT}
 
 type (
 	// Context represents the main application context
 	Context struct {
-		Provider  string
-		Operation string
-		Filename  string
-		Query     string
-		ID        string
+		Request
+		Filename   string
+		Fixtures   string
+		Record     bool
+		Addr       string
+		CacheDir   string
+		NoCache    bool
+		MaxAge     time.Duration
+		CacheTTLs  map[string]time.Duration
+		Input      string
+		Workers    int
+		Retries    int
+		RetryWait  time.Duration
+		Rates      map[string]float64
+		Robots     bool
+		Timeout    time.Duration
+		Format     string
+		OutputMode string
+	}
+
+	// Request represents a single operation to run
+	Request struct {
+		Provider  string `json:"provider"`
+		Operation string `json:"op"`
+		Query     string `json:"query,omitempty"`
+		Year      uint   `json:"year,omitempty"`
+		ID        string `json:"id,omitempty"`
+		// Type is the kind of titles listed by search operations
+		Type string `json:"type,omitempty"`
+		// Offset and Limit select the page of results of search operations
+		Offset uint `json:"offset,omitempty"`
+		Limit  uint `json:"limit,omitempty"`
+		// Paged outputs the SearchPage of search operations instead of only
+		// its results
+		Paged bool `json:"paged,omitempty"`
+		// Ratings also fetches the rating distribution in IMDb score operations
+		Ratings bool `json:"ratings,omitempty"`
+		// Weights are the weights of each provider in aggregate operations
+		Weights map[string]float64 `json:"weights,omitempty"`
+		// Threshold is the smallest score change reported by diff operations
+		Threshold float32 `json:"threshold,omitempty"`
 	}
 
 	// TODO: Make one result struct for both operations?
 
 	// ScoreResult represents the result for a score operation
 	ScoreResult struct {
-		Provider   string  `json:"provider"`
-		ID         string  `json:"id"`
-		Score      float32 `json:"score"`
-		ScoreClass string  `json:"score_class,omitempty"`
+		Provider string  `json:"provider"`
+		ID       string  `json:"id"`
+		Kind     string  `json:"kind,omitempty"`
+		Score    float32 `json:"score"`
+		// NormalizedScore is Score in a 0-100 scale, so scores of different
+		// providers can be compared
+		NormalizedScore float32 `json:"normalized_score"`
+		ScoreClass      string  `json:"score_class,omitempty"`
+		// CriticCount is the number of critic reviews behind the score
+		CriticCount uint `json:"critic_count,omitempty"`
+		// Audience fields are only available for providers with a separate
+		// score from its users, e.g. RottenTomatoes Audience Score
+		AudienceScore float32 `json:"audience_score,omitempty"`
+		AudienceClass string  `json:"audience_class,omitempty"`
+		AudienceCount uint    `json:"audience_count,omitempty"`
+		// Votes, Metascore and Distribution are only available for IMDb.
+		// Distribution requires an extra request to the ratings page.
+		Votes        uint          `json:"votes,omitempty"`
+		Metascore    uint          `json:"metascore,omitempty"`
+		Distribution []RatingVotes `json:"distribution,omitempty"`
+		Meta         *Meta         `json:"meta,omitempty"`
+	}
+
+	// RatingVotes represents the number of votes given to a single rating
+	RatingVotes struct {
+		Rating uint `json:"rating"`
+		Votes  uint `json:"votes"`
 	}
 
 	// SearchResult represents the result for a search operation
 	SearchResult struct {
-		Provider   string  `json:"provider"`
-		ID         string  `json:"id"`
-		Title      string  `json:"title"`
-		Poster     string  `json:"poster"`
-		Score      float32 `json:"score,omitempty"`
-		ScoreClass string  `json:"score_class,omitempty"`
-		Year       uint    `json:"year"`
+		Provider string  `json:"provider"`
+		ID       string  `json:"id"`
+		Kind     string  `json:"kind,omitempty"`
+		Title    string  `json:"title"`
+		Poster   string  `json:"poster"`
+		Score    float32 `json:"score,omitempty"`
+		// NormalizedScore is Score in a 0-100 scale
+		NormalizedScore float32 `json:"normalized_score,omitempty"`
+		ScoreClass      string  `json:"score_class,omitempty"`
+		Year            uint    `json:"year"`
+		// EndYear is the last year of a TV series that ended
+		EndYear uint `json:"end_year,omitempty"`
+		// MatchScore is how well the result matches the searched title and
+		// year, from 0 to 1. Results are sorted by it. Nil on results that
+		// weren't ranked against a query, e.g. franchise titles.
+		MatchScore *float32 `json:"match_score,omitempty"`
+		Meta       *Meta    `json:"meta,omitempty"`
+	}
+
+	// Failure represents the output of an operation that failed
+	Failure struct {
+		Error *ErrorOutput `json:"error"`
 	}
 
-	// Provider is an interface used to reduce equal code
+	// Meta represents information about how a result was retrieved
+	Meta struct {
+		Cache    string `json:"cache,omitempty"`
+		Attempts int    `json:"attempts,omitempty"`
+	}
+
+	// DetailsResult represents the result for a details operation, which is
+	// the metadata of a movie shared by every provider. Runtime is in minutes
+	// and ReleaseDate in the format YYYY-MM-DD.
+	DetailsResult struct {
+		Provider      string   `json:"provider"`
+		ID            string   `json:"id"`
+		Kind          string   `json:"kind,omitempty"`
+		Title         string   `json:"title"`
+		OriginalTitle string   `json:"original_title,omitempty"`
+		Year          uint     `json:"year,omitempty"`
+		Runtime       uint     `json:"runtime,omitempty"`
+		Genres        []string `json:"genres,omitempty"`
+		ContentRating string   `json:"content_rating,omitempty"`
+		Directors     []string `json:"directors,omitempty"`
+		Cast          []string `json:"cast,omitempty"`
+		Synopsis      string   `json:"synopsis,omitempty"`
+		Poster        string   `json:"poster,omitempty"`
+		ReleaseDate   string   `json:"release_date,omitempty"`
+		Meta          *Meta    `json:"meta,omitempty"`
+	}
+
+	// Provider is an interface used to reduce equal code. Canceling the given
+	// context aborts every request of the operation.
 	Provider interface {
-		Score(id string) (*ScoreResult, error)
-		Search(query string) ([]SearchResult, error)
+		Score(ctx context.Context, id string) (*ScoreResult, error)
+		Search(ctx context.Context, query string) ([]SearchResult, error)
+		SearchPage(ctx context.Context, query string, page PageRequest) (*SearchPage, error)
+		Details(ctx context.Context, id string) (*DetailsResult, error)
 	}
 )
 
@@ -101,34 +224,28 @@ func GetRandomUserAgent() string {
 	return result
 }
 
-// Get performs a GET request to the given URL
+// Get performs a GET request to the given URL using the default fetcher
 func Get(url string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("User-Agent", GetRandomUserAgent())
+	return GetContext(context.Background(), url)
+}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	response, err := client.Do(req)
+// GetContext performs a GET request to the given URL using the default
+// fetcher. Canceling the context aborts the request.
+func GetContext(ctx context.Context, url string) ([]byte, error) {
+	response, err := DefaultFetcher.Get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
+	return response.Body, nil
+}
 
-	defer response.Body.Close()
-	if response.StatusCode != 200 {
-		// TODO: better handle this
-		return nil, errors.New("response code was not successfull")
-	}
-
-	body, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
+// withTimeout returns a copy of parent that is done after the given timeout.
+// A zero timeout means only parent decides when it's done.
+func withTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
 	}
-	fmt.Println(response.Status)
-	fmt.Println(string(body))
-
-	return body, nil
+	return context.WithTimeout(parent, timeout)
 }
 
 func isArgValid(arg string, collection []string) bool {
@@ -140,6 +257,40 @@ func isArgValid(arg string, collection []string) bool {
 	return false
 }
 
+// parsePairs parses a list of key=value pairs separated by commas, e.g.
+// imdb=2,rotten=0.5, where every key is one of keys and values are converted
+// by parse. Errors name a pair by what it holds and its format, e.g. weight
+// and provider=weight.
+func parsePairs[T any](value, name, format string, keys []string, parse func(string) (T, error)) (map[string]T, error) {
+	result := make(map[string]T)
+	keyName := strings.SplitN(format, "=", 2)[0]
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("%s '%s' must be in the format %s", name, pair, format)
+		}
+
+		key := strings.TrimSpace(parts[0])
+		if !isArgValid(key, keys) {
+			return nil, fmt.Errorf("%s '%s' is not supported", keyName, key)
+		}
+
+		v, err := parse(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%s '%s' is invalid: %s", name, pair, err.Error())
+		}
+		result[key] = v
+	}
+
+	return result, nil
+}
+
 func isOperationSupported(op string) bool {
 	return isArgValid(op, supportedOperations)
 }
@@ -150,13 +301,15 @@ func isProviderSupported(provider string) bool {
 
 func checkArgs() *Context {
 	/**
-	 * -p [Required]
+	 * -p [Required unless operation is match, serve, batch, selftest, people or franchise]
 	 * Provider used in operation.
 	 *
 	 * imdb   - IMDb: https://imdb.com.br/
 	 * rotten - RottenTomatoes: https://www.rottentomatoes.com/
+	 * all    - Every provider above at once. Only available for score operation
+	 *          and requires -id in the format imdb=tt0371746,rotten=/m/iron_man
 	 */
-	provider := flag.String("p", "", "Provider to process (imdb/rotten)")
+	provider := flag.String("p", "", "Provider to process (imdb/rotten/all)")
 
 	/**
 	* -op [Required]
@@ -164,93 +317,678 @@ func checkArgs() *Context {
 	*
 	* search - Uses provider's default search API to search for movies. Returns a list as result.
 	* score  - Uses given ID to retrieve movie score.
+	* details - Uses given ID to retrieve movie metadata like genres, cast and synopsis.
+	* match  - Searches given query in every provider, picks the same movie in all of them and
+	*          retrieves its scores.
+	* serve  - Starts a HTTP server exposing the operations above as a JSON API.
+	* batch  - Runs one search, score or match operation per line of -in and outputs one
+	*          JSON result per line.
+	* selftest - Scores and searches well known titles and checks every field is still
+	*          parsed from the live pages, never from the cache. Exits with a non-zero
+	*          code when any check fails.
+	* people - Searches actors, critics and franchises. Only available for rotten.
+	* franchise - Uses given franchise ID, e.g. /franchise/iron_man, to list its titles
+	*          along with their scores. Only available for rotten.
+	* aggregate - Uses given list of provider=id to merge the scores of every provider
+	*          into a single 0-100 score. See -weights.
+	* history - Uses given ID to list every score recorded in -history.
+	* diff - Lists titles whose normalized score changed by more than -threshold
+	*          between the two latest scores recorded in -history.
 	 */
-	operation := flag.String("op", "", "Operation to execute (search/score)")
+	operation := flag.String("op", "", "Operation to execute (search/score/details/match/serve/batch/selftest/people/franchise/aggregate/history/diff)")
 
 	/**
-	* -out [Required]
-	* Filename of the outputted file with results.
+	* -out [Required unless operation is serve]
+	* Filename of the outputted file with results. Use - for standard output.
 	 */
-	filename := flag.String("out", "", "Filename to output")
+	filename := flag.String("out", "", "Filename to output, - for standard output")
+	/**
+	* -format [Optional]
+	* Format of the outputted results.
+	*
+	* json        - Default.
+	* json-pretty - Indented JSON.
+	* ndjson      - One JSON per line, e.g. one per search result.
+	* csv         - One line per result with a header row. Nested fields are
+	*               flattened into columns like meta_cache.
+	* table       - Same columns as csv aligned for reading in a terminal.
+	*
+	* Batch operations always output ndjson.
+	 */
+	format := flag.String("format", FormatJSON, "Output format (json/json-pretty/ndjson/csv/table)")
+	/**
+	* -no-clobber [Optional]
+	* Fail instead of replacing the -out file if it already exists. By default
+	* an existing file is replaced.
+	 */
+	noClobber := flag.Bool("no-clobber", false, "Don't replace the output file if it exists")
+	/**
+	* -append [Optional]
+	* Add results to the end of the -out file if it already exists. Useful
+	* along with ndjson and csv formats.
+	 */
+	appendOutput := flag.Bool("append", false, "Append to the output file if it exists")
 
 	/**
-	* -q [Required if operation is search]
+	* -q [Required if operation is search, match or people]
 	* Query used in search operations.
 	 */
 	query := flag.String("q", "", "Query used in search operations")
 
 	/**
-	* -id [Required if operation is score]
-	* Identifier used in score operations.
+	* -year [Optional]
+	* Release year used to pick the right movie in match operations and to
+	* rank search results. Titles released a year before or after still rank
+	* high, as providers may disagree on the release year.
+	 */
+	year := flag.Uint("year", 0, "Release year used in match and search operations")
+	/**
+	* -type [Optional]
+	* Kind of titles listed by search operations.
+	*
+	* movie - Movies only. Default.
+	* tv    - TV series only.
+	* any   - Movies and TV series.
+	 */
+	kind := flag.String("type", KindMovie, "Kind of titles to search (movie/tv/any)")
+	/**
+	* -limit [Optional]
+	* Number of results of search operations. Defaults to 10, at most 100.
+	 */
+	limit := flag.Uint("limit", DefaultSearchLimit, "Number of results of search operations")
+	/**
+	* -page [Optional]
+	* Page of results of search operations starting at 1. Pages have -limit
+	* results. Can't be used along with -offset.
+	 */
+	page := flag.Uint("page", 0, "Page of results of search operations")
+	/**
+	* -offset [Optional]
+	* Number of results of search operations to skip.
+	 */
+	offset := flag.Uint("offset", 0, "Number of results of search operations to skip")
+	/**
+	* -paged [Optional]
+	* Output search results as an object with the total, offset and limit of the
+	* page along with its results. By default search operations output just the
+	* list of results.
+	 */
+	paged := flag.Bool("paged", false, "Output search results along with total, offset and limit")
+
+	/**
+	* -id [Required if operation is score, details, franchise, aggregate or history]
+	* Identifier used in score operations. When provider is all, a list of
+	* provider=id separated by commas. RottenTomatoes TV series and seasons
+	* are identified by their path, e.g. /tv/game_of_thrones/s08.
 	 */
 	id := flag.String("id", "", "Identifier used in score operations")
 
+	/**
+	* -fixtures [Optional]
+	* Directory with saved responses. When defined, pages are served from it
+	* instead of being fetched from the providers.
+	 */
+	fixtures := flag.String("fixtures", "", "Directory with saved responses to replay")
+
+	/**
+	* -record [Optional]
+	* Fetch responses missing from -fixtures directory and save them there.
+	 */
+	record := flag.Bool("record", false, "Record responses missing from -fixtures directory")
+
+	/**
+	* -addr [Optional]
+	* Address the HTTP server listens on in serve operations.
+	 */
+	addr := flag.String("addr", ":8080", "Address to listen on in serve operation")
+
+	/**
+	* -cache-dir [Optional]
+	* Directory where responses are cached. Defaults to movie-scores inside the
+	* user cache directory.
+	 */
+	cacheDir := flag.String("cache-dir", DefaultCacheDir(), "Directory where responses are cached")
+
+	/**
+	* -no-cache [Optional]
+	* Always fetch responses from the providers.
+	 */
+	noCache := flag.Bool("no-cache", false, "Disable the response cache")
+
+	/**
+	* -max-age [Optional]
+	* Maximum age of a cached response for every operation, e.g. 30m. Overrides
+	* -cache-ttl.
+	 */
+	maxAge := flag.Duration("max-age", 0, "Maximum age of cached responses")
+
+	/**
+	* -cache-ttl [Optional]
+	* Time to live of cached responses per operation, e.g. search=1h,score=24h.
+	 */
+	cacheTTL := flag.String("cache-ttl", "", "Time to live of cached responses per operation")
+
+	/**
+	* -in [Optional]
+	* File with one operation per line used in batch operations. Either a JSON
+	* object like {"provider": "imdb", "op": "score", "id": "tt0371746"} or
+	* provider, operation and query/id separated by spaces like
+	* rotten search iron man. Defaults to standard input.
+	 */
+	input := flag.String("in", "-", "File with one operation per line used in batch operation")
+
+	/**
+	* -workers [Optional]
+	* Maximum number of batch operations running at the same time.
+	 */
+	workers := flag.Int("workers", 4, "Number of concurrent batch operations")
+
+	/**
+	* -retries [Optional]
+	* Number of times a request is retried after a network error, a timeout or
+	* a rate limited/unavailable response.
+	 */
+	retries := flag.Int("retries", 2, "Number of retries of failed requests")
+
+	/**
+	* -retry-max-wait [Optional]
+	* Longest wait between two attempts of a request. Requests aren't retried
+	* when the provider asks to wait longer through Retry-After header.
+	 */
+	retryMaxWait := flag.Duration("retry-max-wait", 30*time.Second, "Longest wait between retries")
+
+	/**
+	* -rate [Optional]
+	* Maximum requests per second sent to each provider, e.g. imdb=2,rotten=0.5.
+	* Zero means no limit. Providers not listed keep their default rate.
+	 */
+	rate := flag.String("rate", "", "Maximum requests per second per provider")
+
+	/**
+	* -robots [Optional]
+	* Refuse to fetch paths disallowed by the robots.txt of each provider.
+	 */
+	robots := flag.Bool("robots", false, "Respect robots.txt of each provider")
+
+	/**
+	* -timeout [Optional]
+	* Maximum duration of an operation including retries, e.g. 45s. In batch
+	* and serve operations it applies to each line or HTTP request. Zero means
+	* no limit.
+	 */
+	timeout := flag.Duration("timeout", 30*time.Second, "Maximum duration of an operation")
+	/**
+	* -ratings [Optional]
+	* Also fetch the number of votes per rating from the ratings page in IMDb
+	* score operations. Costs one extra request.
+	 */
+	ratings := flag.Bool("ratings", false, "Fetch IMDb rating distribution in score operations")
+	/**
+	* -selectors [Optional]
+	* JSON file with the rules used to extract fields from provider pages.
+	* Replaces the rules embedded in the binary, so a redesigned page can be
+	* parsed again without a rebuild. See selectors.json.
+	 */
+	selectorsFile := flag.String("selectors", "", "JSON file with page extraction rules")
+	/**
+	* -weights [Optional]
+	* Weight of each provider in aggregate operations, e.g. imdb=2,rotten=1.
+	* Providers not listed weigh 1 and zero leaves a provider out. Scores
+	* backed by few votes or reviews count less than their weight.
+	 */
+	weightsList := flag.String("weights", "", "Weight of each provider in aggregate operations")
+	/**
+	* -history [Optional]
+	* File where every score is recorded, one JSON per line. Required by
+	* history and diff operations. Score pages skip the cache while it's set,
+	* so every run records a fresh score.
+	 */
+	historyFile := flag.String("history", "", "File to record every score")
+	/**
+	* -threshold [Optional]
+	* Smallest change of a normalized score, from 0 to 100, reported by diff
+	* operations.
+	 */
+	threshold := flag.Float64("threshold", DefaultHistoryThreshold, "Smallest score change reported by diff operations")
+	/**
+	* -v [Optional]
+	* Log more on standard error. -v logs every request with its status,
+	* latency and size along with retry and cache decisions. -v -v also dumps
+	* response bodies to -dump-dir.
+	 */
+	var verbose verbosity
+	flag.Var(&verbose, "v", "Verbose logging, repeat for trace level")
+	/**
+	* -quiet [Optional]
+	* Only log errors. Takes precedence over -v.
+	 */
+	quiet := flag.Bool("quiet", false, "Only log errors")
+	/**
+	* -log-format [Optional]
+	* Format of log lines, text (default) or json.
+	 */
+	logFormat := flag.String("log-format", LogFormatText, "Format of log lines (text/json)")
+	/**
+	* -dump-dir [Optional]
+	* Directory where response bodies are dumped at trace level (-v -v). Dumps
+	* are named like fixtures, so the directory can be used with -fixtures.
+	* Bodies of failed responses get their status appended, e.g. _503.
+	 */
+	dumpDir := flag.String("dump-dir", "", "Directory to dump response bodies at trace level")
+
 	flag.Parse()
 
-	if *provider == "" || *operation == "" || *filename == "" {
-		log.Fatalf("Error: all parameters must be defined")
+	if !isArgValid(*logFormat, supportedLogFormats) {
+		log.Fatalf("Error: log format '%s' is not supported", *logFormat)
 	}
+	DefaultLogger = NewLogger(os.Stderr, logLevel(verbose, *quiet), *logFormat)
+	DefaultLogger.DumpDir = *dumpDir
 
-	if !isProviderSupported(*provider) {
-		log.Fatalf("Error: provider '%s' is not supported", *provider)
+	if *historyFile != "" {
+		DefaultHistory = NewHistoryStore(*historyFile)
+	} else if *operation == opHistory || *operation == opDiff {
+		log.Fatalf("Error: history file is required for %s operation", *operation)
 	}
 
-	if !isOperationSupported(*operation) {
-		log.Fatalf("Error: operation '%s' is not supported", *operation)
+	if *selectorsFile != "" {
+		selectors, err := LoadSelectors(*selectorsFile)
+		if err != nil {
+			log.Fatalf("Error: %s", err.Error())
+		}
+		DefaultSelectors = selectors
 	}
 
-	if *operation == "search" && *query == "" {
-		log.Fatalf("Error: query is required for search operation")
+	ttls, err := parseTTLs(*cacheTTL)
+	if err != nil {
+		log.Fatalf("Error: %s", err.Error())
 	}
 
-	if *operation == "score" && *id == "" {
-		log.Fatalf("Error: id is required for score operation")
+	rates := make(map[string]float64)
+	for provider, r := range DefaultProviderRates {
+		rates[provider] = r
+	}
+	customRates, err := parseRates(*rate)
+	if err != nil {
+		log.Fatalf("Error: %s", err.Error())
+	}
+	for provider, r := range customRates {
+		rates[provider] = r
 	}
 
-	return &Context{
-		Provider:  *provider,
+	weights, err := parseWeights(*weightsList)
+	if err != nil {
+		log.Fatalf("Error: %s", err.Error())
+	}
+
+	if *page > 0 && *offset > 0 {
+		log.Fatalf("Error: page and offset can't be used together")
+	}
+	if *page > 0 {
+		*offset = (*page - 1) * PageRequest{Limit: *limit}.limit()
+	}
+
+	if *record && *fixtures == "" {
+		log.Fatalf("Error: fixtures directory is required to record responses")
+	}
+
+	if *retries < 0 {
+		log.Fatalf("Error: retries can't be negative")
+	}
+
+	if *retryMaxWait <= 0 {
+		log.Fatalf("Error: retry-max-wait must be greater than zero")
+	}
+
+	ctx := &Context{
+		Request: Request{
+			Provider:  *provider,
+			Operation: *operation,
+			Query:     *query,
+			Year:      *year,
+			ID:        *id,
+			Type:      *kind,
+			Offset:    *offset,
+			Limit:     *limit,
+			Paged:     *paged,
+			Ratings:   *ratings,
+			Weights:   weights,
+			Threshold: float32(*threshold),
+		},
 		Filename:  *filename,
-		Operation: *operation,
-		Query:     *query,
-		ID:        *id,
+		Fixtures:  *fixtures,
+		Record:    *record,
+		Addr:      *addr,
+		CacheDir:  *cacheDir,
+		NoCache:   *noCache,
+		MaxAge:    *maxAge,
+		CacheTTLs: ttls,
+		Input:     *input,
+		Workers:   *workers,
+		Retries:   *retries,
+		RetryWait: *retryMaxWait,
+		Rates:     rates,
+		Robots:    *robots,
+		Timeout:   *timeout,
+		Format:    *format,
+	}
+
+	switch {
+	case *noClobber && *appendOutput:
+		log.Fatalf("Error: no-clobber and append can't be used together")
+	case *noClobber:
+		ctx.OutputMode = OutputCreate
+	case *appendOutput:
+		ctx.OutputMode = OutputAppend
+	default:
+		ctx.OutputMode = OutputOverwrite
+	}
+
+	if *operation != "" && !isOperationSupported(*operation) {
+		log.Fatalf("Error: operation '%s' is not supported", *operation)
+	}
+
+	switch *operation {
+	case opServe:
+		return ctx
+	case opBatch:
+		if *filename == "" {
+			log.Fatalf("Error: filename is required for batch operation")
+		}
+		if *workers < 1 {
+			log.Fatalf("Error: workers must be at least 1")
+		}
+		if *format != FormatJSON && *format != FormatNDJSON {
+			log.Fatalf("Error: batch operation only outputs %s", FormatNDJSON)
+		}
+		return ctx
+	}
+
+	if !isArgValid(*format, supportedFormats) {
+		log.Fatalf("Error: format '%s' is not supported", *format)
 	}
+
+	if *operation == "" || *filename == "" {
+		log.Fatalf("Error: all parameters must be defined")
+	}
+
+	if err := ctx.Request.validate(); err != nil {
+		log.Fatalf("Error: %s", err.Error())
+	}
+
+	return ctx
 }
 
-func (ctx *Context) run() {
-	var p Provider
-
-	if ctx.Provider == IMDB {
-		p = NewIMDb()
-	} else if ctx.Provider == RottenT {
-		p = NewRottenTomatoes()
-	}
-
-	if p != nil {
-		var result interface{}
-		var err error
-
-		switch ctx.Operation {
-		case opSearch:
-			result, err = p.Search(ctx.Query)
-			if err != nil {
-				log.Fatal(err)
-			}
-		case opScore:
-			result, err = p.Score(ctx.ID)
-			if err != nil {
-				log.Fatal(err)
-			}
-		default:
+// validate checks whether the request has everything its operation needs
+func (req *Request) validate() error {
+	if isArgValid(req.Operation, []string{opMatch, opSelftest, opAggregate, opDiff}) && req.Provider == "" {
+		req.Provider = AllProviders
+	}
+
+	if (req.Operation == opPeople || req.Operation == opFranchise) && req.Provider == "" {
+		req.Provider = RottenT
+	}
+
+	if req.Type == "" {
+		req.Type = KindMovie
+	}
+
+	if !isArgValid(req.Type, supportedKinds) {
+		return fmt.Errorf("type '%s' is not supported", req.Type)
+	}
+
+	if req.Limit > MaxSearchLimit {
+		return fmt.Errorf("limit can't be greater than %d", MaxSearchLimit)
+	}
+
+	if req.Provider == "" || req.Operation == "" {
+		return errors.New("provider and operation must be defined")
+	}
+
+	if req.Provider != AllProviders && !isProviderSupported(req.Provider) {
+		return fmt.Errorf("provider '%s' is not supported", req.Provider)
+	}
+
+	if !isArgValid(req.Operation, requestOperations) {
+		return fmt.Errorf("operation '%s' is not supported", req.Operation)
+	}
+
+	if isArgValid(req.Operation, []string{opSearch, opMatch, opPeople}) && req.Query == "" {
+		return fmt.Errorf("query is required for %s operation", req.Operation)
+	}
+
+	if isArgValid(req.Operation, idOperations) && req.ID == "" {
+		return fmt.Errorf("id is required for %s operation", req.Operation)
+	}
+
+	if (req.Operation == opPeople || req.Operation == opFranchise) && req.Provider != RottenT {
+		return fmt.Errorf("%s operation is only supported by provider '%s'", req.Operation, RottenT)
+	}
+
+	if req.Provider == AllProviders && !isArgValid(req.Operation, []string{opScore, opMatch, opSelftest, opAggregate, opDiff}) {
+		return fmt.Errorf("provider '%s' is only supported by score, match, selftest, aggregate and diff operations", req.Provider)
+	}
+
+	if req.Operation == opAggregate && req.Provider != AllProviders {
+		return errors.New("aggregate operation uses every provider")
+	}
+
+	if req.Operation == opMatch && req.Provider != AllProviders {
+		return errors.New("match operation uses every provider")
+	}
+
+	// Only score results have a rating distribution
+	if req.Ratings && req.Operation != opScore {
+		return errors.New("ratings are only supported by score operation")
+	}
+
+	return nil
+}
+
+// execute runs the request and returns its result
+func (req *Request) execute(ctx context.Context, fetcher Fetcher) (interface{}, error) {
+	if req.Operation == opMatch {
+		return Match(ctx, req.Query, req.Year, fetcher)
+	}
+
+	if req.Operation == opSelftest {
+		return Selftest(ctx, fetcher, selftestCanaries(req.Provider)), nil
+	}
+
+	if req.Operation == opHistory {
+		return DefaultHistory.Series(req.Provider, req.ID)
+	}
+
+	if req.Operation == opDiff {
+		return DefaultHistory.Diff(req.Provider, req.Threshold)
+	}
+
+	if req.Provider == AllProviders {
+		ids, err := parseProviderIDs(req.ID)
+		if err != nil {
+			return nil, err
+		}
+		if req.Operation == opAggregate {
+			return Aggregate(ctx, ids, req.Weights, fetcher)
 		}
+		return ScoreAll(ctx, ids, fetcher, req.Ratings), nil
+	}
+
+	p := withRatings(newProvider(req.Provider, fetcher), req.Ratings)
+	switch req.Operation {
+	case opSearch:
+		page, err := p.SearchPage(ctx, req.Query, PageRequest{
+			Kind:   req.Type,
+			Year:   req.Year,
+			Offset: req.Offset,
+			Limit:  req.Limit,
+		})
+		if err != nil || req.Paged {
+			return page, err
+		}
+		return page.Results, nil
+	case opScore:
+		return score(ctx, p, req.ID)
+	case opDetails:
+		return p.Details(ctx, req.ID)
+	case opPeople:
+		return p.(*RottenTomatoes).People(ctx, req.Query)
+	case opFranchise:
+		return p.(*RottenTomatoes).Franchise(ctx, req.ID)
+	}
+
+	return nil, fmt.Errorf("operation '%s' is not supported", req.Operation)
+}
 
-		if result != nil {
-			r := OutputFile(ctx.Filename, result)
-			fmt.Printf("Outputted to: %s\n", r.Filename)
+func (ctx *Context) fetcher() Fetcher {
+	upstream := NewHTTPFetcher()
+	upstream.Retries = ctx.Retries
+	upstream.RetryMaxWait = ctx.RetryWait
+	// Every provider shares the same limiter, so the rate of a host holds
+	// no matter how many operations run at the same time
+	upstream.Limiter = NewHostLimiter(ctx.Rates)
+	upstream.CheckRobots = ctx.Robots
+
+	// Fixtures are already stored locally, no need to cache them
+	if ctx.Fixtures != "" {
+		if ctx.Record {
+			return NewRecordFetcher(ctx.Fixtures, upstream)
 		}
+		return NewReplayFetcher(ctx.Fixtures)
+	}
+
+	if ctx.NoCache || ctx.CacheDir == "" {
+		return upstream
+	}
+
+	cache := NewCacheFetcher(ctx.CacheDir, upstream)
+	cache.MaxAge = ctx.MaxAge
+	for op, ttl := range ctx.CacheTTLs {
+		cache.TTLs[op] = ttl
+	}
+	// A score served from the cache was already recorded, so runs within its
+	// time to live would leave gaps in the history
+	if DefaultHistory != nil {
+		cache.Bypass = []string{opScore}
+	}
+	return cache
+}
+
+func newProvider(name string, fetcher Fetcher) Provider {
+	switch name {
+	case IMDB:
+		return NewIMDb(fetcher)
+	case RottenT:
+		return NewRottenTomatoes(fetcher)
+	}
+	return nil
+}
+
+// withRatings makes the given provider also fetch the rating distribution in
+// score operations when ratings is set. Only IMDb has one.
+func withRatings(p Provider, ratings bool) Provider {
+	if imdb, ok := p.(*IMDb); ok {
+		imdb.FetchRatings = ratings
+	}
+	return p
+}
+
+// score returns the score of the given id retrieved by the provider and
+// records it in DefaultHistory. Every operation retrieving scores uses it.
+func score(ctx context.Context, p Provider, id string) (*ScoreResult, error) {
+	result, err := p.Score(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, &ProviderError{Kind: ErrNotFound, Err: fmt.Errorf("no score found for %s", id)}
+	}
+
+	recordScore(result)
+	return result, nil
+}
+
+// run executes the operation of the application context. Canceling parent
+// aborts the operation.
+func (ctx *Context) run(parent context.Context) {
+	switch ctx.Operation {
+	case opServe:
+		server := NewServer(ctx.fetcher())
+		server.Timeout = ctx.Timeout
+
+		DefaultLogger.Info("listening", "addr", ctx.Addr)
+		log.Fatal(server.ListenAndServe(ctx.Addr))
+	case opBatch:
+		ctx.runBatch(parent)
+		return
+	}
+
+	opCtx, cancel := withTimeout(parent, ctx.Timeout)
+	result, err := ctx.execute(opCtx, ctx.fetcher())
+	cancel()
+	if err != nil {
+		// Let the caller know what went wrong through both the output file and
+		// the exit code
+		if _, outErr := Output(ctx.Filename, ctx.Format, ctx.OutputMode, Failure{Error: newErrorOutput(err)}); outErr != nil {
+			DefaultLogger.Error("output failed", "error", outErr)
+		}
+		DefaultLogger.Error("operation failed", "op", ctx.Operation, "error", err)
+		os.Exit(exitCode(err))
+	}
+
+	r, err := Output(ctx.Filename, ctx.Format, ctx.OutputMode, result)
+	if err != nil {
+		DefaultLogger.Error("output failed", "error", err)
+		os.Exit(ExitFailure)
+	}
+	if r.Filename != Stdout {
+		DefaultLogger.Info("outputted", "file", r.Filename)
+	}
+
+	if report, ok := result.(*SelftestReport); ok && !report.OK {
+		DefaultLogger.Error("selftest failed", "failed", report.Failed)
+		os.Exit(ExitSelftestFailed)
+	}
+}
+
+func (ctx *Context) runBatch(parent context.Context) {
+	in := os.Stdin
+	if ctx.Input != "-" {
+		file, err := os.Open(ctx.Input)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	out := os.Stdout
+	if ctx.Filename != Stdout {
+		file, err := openBatchOutput(ctx.Filename, ctx.OutputMode)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	batch := &Batch{
+		Fetcher: ctx.fetcher(),
+		Workers: ctx.Workers,
+		Timeout: ctx.Timeout,
+	}
+	if err := batch.Run(parent, in, out); err != nil {
+		log.Fatal(err)
+	}
+	if out != os.Stdout {
+		DefaultLogger.Info("outputted", "file", out.Name())
 	}
 }
 
 func main() {
+	// Interrupting the application aborts every outstanding request
+	parent, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	ctx := checkArgs()
-	ctx.run()
+	ctx.run(parent)
 }
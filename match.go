@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+type (
+	// MatchResult represents the result for a match operation, which is the
+	// same movie found in every provider followed by its scores
+	MatchResult struct {
+		Title      string                `json:"title"`
+		Year       uint                  `json:"year"`
+		Confidence float32               `json:"confidence"`
+		Matches    []SearchResult        `json:"matches"`
+		Scores     []ProviderScoreResult `json:"scores"`
+	}
+
+	matchCandidate struct {
+		imdb       SearchResult
+		rotten     SearchResult
+		confidence float32
+	}
+)
+
+// Match searches the given title in IMDb and RottenTomatoes, pairs the
+// candidates of both providers by title and year and returns the best pair
+// along with its scores. A year of zero means any year.
+func Match(ctx context.Context, title string, year uint, fetcher Fetcher) (*MatchResult, error) {
+	if title == "" {
+		return nil, errors.New("title is empty")
+	}
+
+	results := make(map[string][]SearchResult)
+	errs := make(map[string]error)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, provider := range []string{IMDB, RottenT} {
+		wg.Add(1)
+		go func(provider string) {
+			defer wg.Done()
+
+			r, err := newProvider(provider, fetcher).Search(ctx, title)
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[provider] = r
+			errs[provider] = err
+		}(provider)
+	}
+	wg.Wait()
+
+	for _, provider := range []string{IMDB, RottenT} {
+		if errs[provider] != nil {
+			return nil, fmt.Errorf("%s search failed: %w", provider, errs[provider])
+		}
+		// Only movies are matched, a series may share the title of a movie
+		results[provider] = filterKind(results[provider], KindMovie)
+	}
+
+	best := bestMatch(title, year, results[IMDB], results[RottenT])
+	if best == nil {
+		return nil, &ProviderError{Kind: ErrNotFound, Err: fmt.Errorf("couldn't find a match for %s", title)}
+	}
+
+	ids := map[string]string{
+		IMDB:    best.imdb.ID,
+		RottenT: best.rotten.ID,
+	}
+
+	result := &MatchResult{
+		Title:      best.imdb.Title,
+		Year:       best.imdb.Year,
+		Confidence: best.confidence,
+		Matches:    []SearchResult{best.imdb, best.rotten},
+		Scores:     ScoreAll(ctx, ids, fetcher, false).Results,
+	}
+	if result.Year == 0 {
+		result.Year = best.rotten.Year
+	}
+
+	return result, nil
+}
+
+// bestMatch returns the pair of candidates with the highest confidence or nil
+// if no pair looks like the same movie
+func bestMatch(title string, year uint, imdb, rotten []SearchResult) *matchCandidate {
+	var best *matchCandidate
+
+	for _, a := range imdb {
+		for _, b := range rotten {
+			pair := titleSimilarity(a.Title, b.Title) * yearSimilarity(a.Year, b.Year)
+			if pair == 0 {
+				continue
+			}
+
+			query := (titleSimilarity(title, a.Title) + titleSimilarity(title, b.Title)) / 2
+			if year > 0 {
+				query *= yearSimilarity(year, a.Year)
+			}
+
+			confidence := pair * query
+			// Candidates come in provider's relevance order, so only replace
+			// the best one by a strictly better pair
+			if confidence > 0 && (best == nil || confidence > best.confidence) {
+				best = &matchCandidate{
+					imdb:       a,
+					rotten:     b,
+					confidence: confidence,
+				}
+			}
+		}
+	}
+
+	return best
+}
+
+// titleArticles are left out of normalized titles, so "The Lion King" and
+// "Lion King, The" are the same title
+var titleArticles = map[string]bool{
+	"a":   true,
+	"an":  true,
+	"the": true,
+}
+
+// normalizeTitle lowercases the given title, strips accents and articles and
+// replaces punctuation by spaces so titles written differently by each
+// provider can be compared. A title made only of articles keeps them.
+func normalizeTitle(title string) string {
+	words := strings.Fields(foldTitle(title))
+
+	result := make([]string, 0, len(words))
+	for _, w := range words {
+		if !titleArticles[w] {
+			result = append(result, w)
+		}
+	}
+	if len(result) == 0 {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(result, " ")
+}
+
+// foldTitle lowercases the given title, strips accents and replaces
+// punctuation by spaces, e.g. "Amélie" -> "amelie". Apostrophes are dropped
+// instead, so "Schindler's List" -> "schindlers list".
+func foldTitle(title string) string {
+	var b strings.Builder
+	for _, r := range title {
+		switch {
+		case r == '\'' || r == '’':
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			r = unicode.ToLower(r)
+			if folded, ok := accentFolds[r]; ok {
+				b.WriteString(folded)
+			} else {
+				b.WriteRune(r)
+			}
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// accentFolds are the letters without accents of lowercase latin letters
+var accentFolds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae",
+	'ç': "c", 'ć': "c", 'č': "c",
+	'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l",
+	'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'œ': "oe",
+	'ř': "r",
+	'ß': "ss", 'ś': "s", 'š': "s", 'ş': "s",
+	'ť': "t", 'ţ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// titleSimilarity returns a value between 0 and 1 which is the Sørensen–Dice
+// coefficient of the words of both normalized titles
+func titleSimilarity(a, b string) float32 {
+	a = normalizeTitle(a)
+	b = normalizeTitle(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	words := make(map[string]int)
+	for _, w := range strings.Fields(a) {
+		words[w]++
+	}
+
+	total := len(strings.Fields(a))
+	common := 0
+	for _, w := range strings.Fields(b) {
+		total++
+		if words[w] > 0 {
+			words[w]--
+			common++
+		}
+	}
+
+	return float32(2*common) / float32(total)
+}
+
+// yearSimilarity returns a value between 0 and 1 for the given years. Release
+// years may differ by one between providers, and an unknown year neither
+// confirms nor discards a match.
+func yearSimilarity(a, b uint) float32 {
+	if a == 0 || b == 0 {
+		return 0.8
+	}
+	switch {
+	case a == b:
+		return 1
+	case a == b+1 || b == a+1:
+		return 0.9
+	}
+	return 0
+}
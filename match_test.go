@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	result, err := Match(context.Background(), "iron man", 2008, testFetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Matches) != 2 {
+		t.Fatalf("Size was incorrect, got: %d, expected: 2", len(result.Matches))
+	}
+
+	if result.Matches[0].ID != "tt0371746" || result.Matches[1].ID != "/m/iron_man" {
+		t.Errorf("Matches were incorrect, got: %s and %s", result.Matches[0].ID, result.Matches[1].ID)
+	}
+
+	if result.Confidence != 1 {
+		t.Errorf("Confidence was incorrect, got: %f, expected: 1", result.Confidence)
+	}
+
+	for _, score := range result.Scores {
+		if score.Error != nil {
+			t.Errorf("Score for %s failed: %s", score.Provider, score.Error.Message)
+		}
+	}
+}
+
+func TestBestMatchYear(t *testing.T) {
+	imdb := []SearchResult{
+		{ID: "tt6105098", Title: "The Lion King", Year: 2019},
+		{ID: "tt0110357", Title: "The Lion King", Year: 1994},
+	}
+	rotten := []SearchResult{
+		{ID: "/m/the_lion_king", Title: "The Lion King", Year: 1994},
+		{ID: "/m/the_lion_king_2019", Title: "The Lion King", Year: 2019},
+	}
+
+	best := bestMatch("the lion king", 1994, imdb, rotten)
+	if best == nil {
+		t.Fatalf("Match was not found")
+	}
+
+	if best.imdb.ID != "tt0110357" || best.rotten.ID != "/m/the_lion_king" {
+		t.Errorf("Match was incorrect, got: %s and %s", best.imdb.ID, best.rotten.ID)
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	if s := titleSimilarity("Spider-Man: Homecoming", "spider man homecoming"); s != 1 {
+		t.Errorf("Similarity was incorrect, got: %f, expected: 1", s)
+	}
+
+	if s := titleSimilarity("Iron Man", "Iron Man 2"); s != 0.8 {
+		t.Errorf("Similarity was incorrect, got: %f, expected: 0.8", s)
+	}
+
+	if s := titleSimilarity("Iron Man", "Sharknado"); s != 0 {
+		t.Errorf("Similarity was incorrect, got: %f, expected: 0", s)
+	}
+}
@@ -1,43 +1,154 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 )
 
+// Stdout is the filename used to output to the standard output
+const Stdout = "-"
+
+// Modes of writing to an output file that already exists
+const (
+	// OutputCreate refuses to replace an existing file, see -no-clobber
+	OutputCreate = "create"
+	// OutputOverwrite replaces the existing file. Default.
+	OutputOverwrite = "overwrite"
+	// OutputAppend adds to the end of the existing file
+	OutputAppend = "append"
+)
+
+// outputPerm is the permission of created output files
+const outputPerm = 0644
+
 type (
 	// OutputResult represents the output of the application, which is a
-	// text file using JSON as its format.
+	// text file using JSON or one of the other formats.
 	OutputResult struct {
 		Filename string
 		Data     string
 	}
 )
 
-// OutputFile outputs struct data to a JSON file.
-func OutputFile(filename string, data interface{}) *OutputResult {
-	if filename == "" || data == nil {
-		return nil
+// OutputFile outputs struct data to a JSON file, replacing it if it exists.
+func OutputFile(filename string, data interface{}) (*OutputResult, error) {
+	return Output(filename, FormatJSON, OutputOverwrite, data)
+}
+
+// Output outputs struct data to a file in the given format, or to the
+// standard output when filename is Stdout. The file is written to a temporary
+// file first and renamed, so readers never see a partially written output.
+func Output(filename, format, mode string, data interface{}) (*OutputResult, error) {
+	if filename == "" {
+		return nil, errors.New("filename is empty")
+	}
+	if data == nil {
+		return nil, errors.New("no data to output")
+	}
+
+	var contents bytes.Buffer
+	if err := Encode(&contents, format, data); err != nil {
+		return nil, err
+	}
+
+	if filename == Stdout {
+		if _, err := os.Stdout.Write(contents.Bytes()); err != nil {
+			return nil, err
+		}
+		return &OutputResult{Filename: filename, Data: contents.String()}, nil
+	}
+
+	existing, err := ioutil.ReadFile(filename)
+	switch {
+	case os.IsNotExist(err):
+		existing = nil
+	case err != nil:
+		return nil, err
+	case mode == OutputCreate:
+		return nil, fmt.Errorf("file '%s' already exists and no-clobber is set", filename)
+	case mode == OutputOverwrite:
+		existing = nil
+	}
+
+	body := contents.Bytes()
+	if len(existing) > 0 && format == FormatCSV {
+		// The existing file already has the header row
+		if i := bytes.IndexByte(body, '\n'); i >= 0 {
+			body = body[i+1:]
+		}
+	}
+
+	if err := writeAtomic(filename, append(existing, body...)); err != nil {
+		return nil, err
+	}
+
+	return &OutputResult{
+		Filename: filename,
+		Data:     contents.String(),
+	}, nil
+}
+
+// openBatchOutput opens the file a batch operation streams its results to.
+// Unlike Output, results are written as they come, so a reader may see a
+// partial last line until the batch is done.
+func openBatchOutput(filename, mode string) (*os.File, error) {
+	flags := os.O_CREATE | os.O_WRONLY
+	switch mode {
+	case OutputOverwrite:
+		flags |= os.O_TRUNC
+	case OutputAppend:
+		flags |= os.O_APPEND
+	default:
+		flags |= os.O_EXCL
+	}
+
+	file, err := os.OpenFile(filename, flags, outputPerm)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("file '%s' already exists and no-clobber is set", filename)
 	}
+	return file, err
+}
+
+// writeAtomic writes data to a temporary file in the same directory, flushes
+// it to disk and renames it to filename
+func writeAtomic(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
 
-	contents, err := json.Marshal(data)
+	tmpfile, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".tmp-")
 	if err != nil {
-		fmt.Printf("Error: %s\n", err.Error())
-		return nil
+		return err
 	}
+	// Removing fails once the file is renamed, which is fine
+	defer os.Remove(tmpfile.Name())
 
-	tmpfile, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY, 0755)
-	if _, err := tmpfile.Write(contents); err != nil {
-		return nil
+	if _, err := tmpfile.Write(data); err != nil {
+		tmpfile.Close()
+		return err
+	}
+	if err := tmpfile.Chmod(outputPerm); err != nil {
+		tmpfile.Close()
+		return err
+	}
+	if err := tmpfile.Sync(); err != nil {
+		tmpfile.Close()
+		return err
 	}
 	if err := tmpfile.Close(); err != nil {
-		return nil
+		return err
 	}
-	// NOTE: Don't forget to remove file from caller after process.
-	// defer os.Remove(tmpfile.Name())
-	return &OutputResult{
-		Filename: tmpfile.Name(),
-		Data:     string(contents),
+
+	if err := os.Rename(tmpfile.Name(), filename); err != nil {
+		return err
+	}
+
+	// Persist the rename too. Not every platform can sync a directory.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
 	}
+	return nil
 }
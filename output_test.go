@@ -3,6 +3,7 @@ package main
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -18,9 +19,9 @@ func TestOutputFile(t *testing.T) {
 		FieldTwo:   "two",
 		FieldThree: []string{"one", "two", "three"},
 	}
-	result := OutputFile("test_file", data)
-	if result == nil {
-		t.Errorf("Result was invalid, got: nil, expected a valid pointer\n")
+	result, err := OutputFile("test_file", data)
+	if err != nil {
+		t.Fatalf("Result was invalid, got: %v, expected a valid pointer\n", err)
 	}
 
 	contents, err := ioutil.ReadFile(result.Filename)
@@ -35,3 +36,68 @@ func TestOutputFile(t *testing.T) {
 		t.Errorf("Contents was invalid, got: %s, expected: %s\n", str, result.Data)
 	}
 }
+
+func TestOutputModes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "out.json")
+	long := TestOutputFormat{FieldOne: 1, FieldTwo: "a much longer value than the next one"}
+	short := TestOutputFormat{FieldOne: 2}
+
+	if _, err := Output(filename, FormatJSON, OutputCreate, long); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Output(filename, FormatJSON, OutputCreate, short); err == nil {
+		t.Errorf("Existing file was replaced without overwrite mode")
+	}
+
+	// A shorter output must not leave bytes of the previous one behind
+	result, err := Output(filename, FormatJSON, OutputOverwrite, short)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, _ := ioutil.ReadFile(filename)
+	if string(contents) != result.Data {
+		t.Errorf("Contents was invalid, got: %s, expected: %s", contents, result.Data)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != outputPerm {
+		t.Errorf("Permissions were incorrect, got: %o, expected: %o", info.Mode().Perm(), outputPerm)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("Temporary files were left behind, got %d files", len(files))
+	}
+}
+
+func TestOutputAppendCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "out.csv")
+	for _, id := range []string{"tt0371746", "tt1228705"} {
+		if _, err := Output(filename, FormatCSV, OutputAppend, &ScoreResult{Provider: IMDB, ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	contents, _ := ioutil.ReadFile(filename)
+	expected := "provider,id,kind,score,normalized_score,score_class,critic_count,audience_score,audience_class,audience_count,votes,metascore,meta_cache,meta_attempts\n" +
+		"imdb,tt0371746,,0,0,,0,0,,0,0,0,,\n" +
+		"imdb,tt1228705,,0,0,,0,0,,0,0,0,,\n"
+	if string(contents) != expected {
+		t.Errorf("Contents was invalid, got: %s, expected: %s", contents, expected)
+	}
+}
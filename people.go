@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Kinds of entities returned by a people operation
+const (
+	EntityActor     = "actor"
+	EntityCritic    = "critic"
+	EntityFranchise = "franchise"
+)
+
+type (
+	// EntityResult represents an entity other than a title found by a people
+	// operation, e.g. an actor. ID is the path used by the provider.
+	EntityResult struct {
+		Provider     string   `json:"provider"`
+		Kind         string   `json:"kind"`
+		ID           string   `json:"id"`
+		Name         string   `json:"name"`
+		Image        string   `json:"image,omitempty"`
+		URL          string   `json:"url"`
+		Publications []string `json:"publications,omitempty"`
+		Meta         *Meta    `json:"meta,omitempty"`
+	}
+
+	// FranchiseResult represents the result for a franchise operation, which
+	// is every title of the franchise along with its score
+	FranchiseResult struct {
+		Provider string         `json:"provider"`
+		ID       string         `json:"id"`
+		Title    string         `json:"title"`
+		Titles   []SearchResult `json:"titles"`
+		Meta     *Meta          `json:"meta,omitempty"`
+	}
+)
+
+// People searches actors, critics and franchises using rotten public api
+func (rt *RottenTomatoes) People(ctx context.Context, query string) ([]EntityResult, error) {
+	if query == "" {
+		return nil, errors.New("query is empty")
+	}
+
+	result, response, err := rt.search(ctx, query, DefaultSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := response.Meta()
+	r := make([]EntityResult, 0)
+	add := func(kind, name, image, path string, publications []string) {
+		r = append(r, EntityResult{
+			Provider:     RottenT,
+			Kind:         kind,
+			ID:           path,
+			Name:         name,
+			Image:        image,
+			URL:          rottenBaseURL + path,
+			Publications: publications,
+			Meta:         meta,
+		})
+	}
+
+	for _, actor := range result.Actors {
+		add(EntityActor, actor.Name, actor.Image, actor.URL, nil)
+	}
+	for _, critic := range result.Critics {
+		add(EntityCritic, critic.Name, critic.Image, critic.URL, critic.Publications)
+	}
+	for _, franchise := range result.Franchises {
+		add(EntityFranchise, franchise.Title, franchise.Image, franchise.URL, nil)
+	}
+
+	return r, nil
+}
+
+// Franchise lists the titles of the given franchise page path, e.g.
+// /franchise/iron_man, along with their scores
+func (rt *RottenTomatoes) Franchise(ctx context.Context, id string) (*FranchiseResult, error) {
+	if id == "" {
+		return nil, errors.New("id is empty")
+	}
+
+	path := franchisePath(id)
+	fullURL := rottenBaseURL + path
+	response, err := rt.fetcher.Get(ctx, fullURL)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response.Body))
+	if err != nil {
+		return nil, parseError(fullURL, err)
+	}
+
+	result := &FranchiseResult{
+		Provider: RottenT,
+		ID:       path,
+		Title:    DefaultSelectors.Extract(doc, RottenT, "franchise_title"),
+		Titles:   make([]SearchResult, 0),
+		Meta:     response.Meta(),
+	}
+
+	DefaultSelectors.Find(doc, RottenT, "franchise_items").Each(func(i int, item *goquery.Selection) {
+		titlePath := DefaultSelectors.ExtractFrom(item, RottenT, "franchise_item_path")
+		if titlePath == "" {
+			return
+		}
+
+		score := float32(scoreAsInt(DefaultSelectors.ExtractFrom(item, RottenT, "franchise_item_score")))
+		result.Titles = append(result.Titles, SearchResult{
+			Provider:        RottenT,
+			ID:              titlePath,
+			Kind:            rottenKind(titlePath),
+			Title:           DefaultSelectors.ExtractFrom(item, RottenT, "franchise_item_title"),
+			Poster:          DefaultSelectors.ExtractFrom(item, RottenT, "franchise_item_poster"),
+			Score:           score,
+			NormalizedScore: normalizeScore(RottenT, score),
+			ScoreClass:      rottenScoreClass(DefaultSelectors.ExtractFrom(item, RottenT, "franchise_item_score_class")),
+			Year:            scoreAsInt(DefaultSelectors.ExtractFrom(item, RottenT, "franchise_item_year")),
+		})
+	})
+
+	if result.Title == "" && len(result.Titles) == 0 {
+		return nil, parseError(fullURL, fmt.Errorf("couldn't find titles of franchise %s", path))
+	}
+
+	return result, nil
+}
+
+// franchisePath returns the page path of the given franchise id, which may be
+// the path or just its name, e.g. iron_man
+func franchisePath(id string) string {
+	id = strings.TrimPrefix(id, "/")
+	if !strings.HasPrefix(id, "franchise/") {
+		id = "franchise/" + id
+	}
+	return "/" + id
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRottenPeople(t *testing.T) {
+	result, err := NewRottenTomatoes(testFetcher).People(context.Background(), "iron man")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := make(map[string]EntityResult)
+	for _, entity := range result {
+		kinds[entity.Kind] = entity
+	}
+
+	actor, ok := kinds[EntityActor]
+	if !ok || actor.ID != "/celebrity/iron_man" || actor.URL != rottenBaseURL+"/celebrity/iron_man" {
+		t.Errorf("Actor was incorrect, got: %+v", actor)
+	}
+
+	franchise, ok := kinds[EntityFranchise]
+	if !ok || franchise.Name != "Iron Man" || franchise.ID != "/franchise/iron_man" {
+		t.Errorf("Franchise was incorrect, got: %+v", franchise)
+	}
+}
+
+func TestRottenFranchise(t *testing.T) {
+	result, err := NewRottenTomatoes(testFetcher).Franchise(context.Background(), "iron_man")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ID != "/franchise/iron_man" || result.Title != "Iron Man" {
+		t.Errorf("Franchise was incorrect, got: %s %s", result.ID, result.Title)
+	}
+
+	if len(result.Titles) != 4 {
+		t.Fatalf("Title count was incorrect, got: %d, expected: 4", len(result.Titles))
+	}
+
+	first := result.Titles[0]
+	if first.ID != "/m/iron_man" || first.Year != 2008 || first.Score != 94 || first.NormalizedScore != 94 || first.ScoreClass != scoreClassCertifiedFresh {
+		t.Errorf("First title was incorrect, got: %+v", first)
+	}
+
+	// Titles without a Tomatometer yet have no score
+	last := result.Titles[3]
+	if last.Kind != KindTV || last.Score != 0 || last.ScoreClass != "" {
+		t.Errorf("Last title was incorrect, got: %+v", last)
+	}
+
+	// Franchise titles aren't ranked against a query
+	data, err := json.Marshal(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "match_score") {
+		t.Errorf("First title had a match score, got: %s", data)
+	}
+}
+
+func TestPeopleRequiresRotten(t *testing.T) {
+	req := Request{Provider: IMDB, Operation: opPeople, Query: "iron man"}
+	if err := req.validate(); err == nil {
+		t.Errorf("People operation was valid for provider %s", IMDB)
+	}
+
+	req = Request{Operation: opFranchise, ID: "iron_man"}
+	if err := req.validate(); err != nil || req.Provider != RottenT {
+		t.Errorf("Franchise operation without provider was incorrect, got: %v with provider %s", err, req.Provider)
+	}
+}
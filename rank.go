@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// rankResults sets how well each result matches the given query and year and
+// sorts the results from the best match to the worst. Results matching
+// equally keep the order of the provider. A year of zero means any year.
+func rankResults(results []SearchResult, query string, year uint) {
+	for i := range results {
+		score := matchScore(query, year, results[i])
+		results[i].MatchScore = &score
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return *results[i].MatchScore > *results[j].MatchScore
+	})
+}
+
+// matchScore returns a value between 0 and 1 for how well the result matches
+// the given query and year. Titles are compared by words and by pairs of
+// letters, so typos and words joined together still match.
+func matchScore(query string, year uint, result SearchResult) float32 {
+	title := (titleSimilarity(query, result.Title) + bigramSimilarity(query, result.Title)) / 2
+	score := title * yearFactor(year, result.Year)
+	return float32(math.Round(float64(score)*1000) / 1000)
+}
+
+// yearFactor returns how much a result released in the given year counts
+// when searching a year, using the same rules as matches. A result of another
+// year still counts half, so it ranks by title among others of other years.
+func yearFactor(year, resultYear uint) float32 {
+	if year == 0 {
+		return 1
+	}
+	if similarity := yearSimilarity(year, resultYear); similarity > 0.5 {
+		return similarity
+	}
+	return 0.5
+}
+
+// bigramSimilarity returns a value between 0 and 1 which is the
+// Sørensen–Dice coefficient of the pairs of letters of both normalized
+// titles, ignoring spaces
+func bigramSimilarity(a, b string) float32 {
+	a = strings.Replace(normalizeTitle(a), " ", "", -1)
+	b = strings.Replace(normalizeTitle(b), " ", "", -1)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	pairs, other := bigrams(a), bigrams(b)
+	total, common := 0, 0
+	for pair, n := range pairs {
+		total += n
+		if m := other[pair]; m < n {
+			common += m
+		} else {
+			common += n
+		}
+	}
+	for _, n := range other {
+		total += n
+	}
+
+	// Single letters have no pairs
+	if total == 0 {
+		return 0
+	}
+	return float32(2*common) / float32(total)
+}
+
+// bigrams returns the number of times each pair of consecutive letters is in
+// the given text
+func bigrams(text string) map[string]int {
+	runes := []rune(text)
+	result := make(map[string]int)
+	for i := 0; i+1 < len(runes); i++ {
+		result[string(runes[i:i+2])]++
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRankResultsYear(t *testing.T) {
+	results := []SearchResult{
+		{ID: "tt6105098", Title: "The Lion King", Year: 2019},
+		{ID: "tt0110357", Title: "The Lion King", Year: 1994},
+		{ID: "tt0120131", Title: "The Lion King II: Simba's Pride", Year: 1998},
+	}
+
+	// Providers may disagree on the release year by one
+	rankResults(results, "lion king", 1995)
+
+	if results[0].ID != "tt0110357" || results[1].ID != "tt6105098" {
+		t.Errorf("Order was incorrect, got: %s, %s and %s", results[0].ID, results[1].ID, results[2].ID)
+	}
+	if results[0].MatchScore == nil || *results[0].MatchScore != 0.9 {
+		t.Errorf("Match score was incorrect, got: %v, expected: 0.9", results[0].MatchScore)
+	}
+}
+
+func TestRankResultsNoMatch(t *testing.T) {
+	results := []SearchResult{{ID: "tt0110357", Title: "The Lion King", Year: 1994}}
+	rankResults(results, "xyz", 0)
+
+	// Ranked results always have a match score, even when nothing matches
+	data, err := json.Marshal(results[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"match_score":0`) {
+		t.Errorf("Match score was missing, got: %s", data)
+	}
+}
+
+func TestRankResultsTitle(t *testing.T) {
+	results := []SearchResult{
+		{ID: "tt0803093", Title: "The Invincible Iron Man"},
+		{ID: "tt1228705", Title: "Iron Man 2"},
+		{ID: "tt0371746", Title: "Iron Man"},
+	}
+
+	rankResults(results, "Iron Mna", 0)
+
+	if results[0].ID != "tt0371746" || results[1].ID != "tt1228705" {
+		t.Errorf("Order was incorrect, got: %s, %s and %s", results[0].ID, results[1].ID, results[2].ID)
+	}
+}
+
+func TestMatchScore(t *testing.T) {
+	cases := []struct {
+		query, title string
+	}{
+		{"amelie", "Amélie"},
+		{"Lion King, The", "The Lion King"},
+		{"schindlers list", "Schindler's List"},
+		{"WALL·E", "Wall-E"},
+	}
+
+	for _, c := range cases {
+		if score := matchScore(c.query, 0, SearchResult{Title: c.title}); score != 1 {
+			t.Errorf("Match score of %s and %s was incorrect, got: %f, expected: 1", c.query, c.title, score)
+		}
+	}
+}
+
+func TestIMDbSearchRanked(t *testing.T) {
+	page, err := NewIMDb(testFetcher).SearchPage(context.Background(), "Iron Man", PageRequest{Kind: KindMovie, Year: 2010})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Results) == 0 || page.Results[0].ID != "tt1228705" {
+		t.Errorf("First result was incorrect, got: %+v", page.Results)
+	}
+	for i := 1; i < len(page.Results); i++ {
+		if *page.Results[i].MatchScore > *page.Results[i-1].MatchScore {
+			t.Errorf("Results were not sorted by match score, got: %+v", page.Results)
+		}
+	}
+}
+
+func TestIMDbSuggestURL(t *testing.T) {
+	cases := map[string]string{
+		"Iron Man": imdbAPIBaseURL + "i/iron_man.json",
+		"Amélie":   imdbAPIBaseURL + "a/amelie.json",
+		"?!":       "",
+	}
+
+	for query, expected := range cases {
+		if url := imdbSuggestURL(query); url != expected {
+			t.Errorf("URL of %s was incorrect, got: %s, expected: %s", query, url, expected)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isRetryable reports whether a failed request may succeed if performed again
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) {
+		// Connection errors, e.g. reset by peer
+		return true
+	}
+
+	switch {
+	case errors.Is(err, ErrRateLimited), errors.Is(err, ErrTimeout):
+		return true
+	case errors.Is(err, ErrUpstream):
+		return providerErr.StatusCode >= 500
+	}
+	return false
+}
+
+// backoff returns how long to wait before the given retry attempt. The wait
+// doubles on every attempt and a random jitter is applied so concurrent
+// requests don't retry all at once.
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	wait := base
+	for i := 1; i < attempt && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	if wait <= 0 {
+		return 0
+	}
+
+	// Equal jitter, keeps half of the wait and randomizes the other half
+	half := int64(wait / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// retryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or a HTTP date. Returns zero when there's nothing to wait.
+func retryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
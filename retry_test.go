@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestHTTPFetcher(retries int) *HTTPFetcher {
+	f := NewHTTPFetcher()
+	f.Retries = retries
+	f.RetryBaseWait = time.Millisecond
+	f.RetryMaxWait = 10 * time.Millisecond
+	return f
+}
+
+func TestHTTPFetcherRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	response, err := newTestHTTPFetcher(2).Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Attempts != 3 || string(response.Body) != "ok" {
+		t.Errorf("Response was incorrect, got: %d attempts and body %s", response.Attempts, response.Body)
+	}
+}
+
+func TestHTTPFetcherNoRetry(t *testing.T) {
+	cases := map[string]http.HandlerFunc{
+		// Not found won't change on a retry
+		"not found": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		},
+		// Provider asks to wait longer than we accept
+		"retry after": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+	}
+
+	for name, handler := range cases {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			handler(w, r)
+		}))
+
+		_, err := newTestHTTPFetcher(3).Get(context.Background(), server.URL)
+		if err == nil {
+			t.Errorf("%s: error was expected", name)
+		}
+		if requests != 1 {
+			t.Errorf("%s: requests were incorrect, got: %d, expected: 1", name, requests)
+		}
+		server.Close()
+	}
+}
+
+func TestHTTPFetcherCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	f := newTestHTTPFetcher(5)
+	f.RetryBaseWait = time.Hour
+	f.RetryMaxWait = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := f.Get(ctx, server.URL)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Error was incorrect, got: %v, expected: %v", err, ErrTimeout)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Retry didn't stop when context was done")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if wait := retryAfter("5"); wait != 5*time.Second {
+		t.Errorf("Wait was incorrect, got: %s, expected: 5s", wait)
+	}
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if wait := retryAfter(date); wait <= 0 || wait > time.Minute {
+		t.Errorf("Wait was incorrect, got: %s, expected: up to 1m", wait)
+	}
+
+	if wait := retryAfter("soon"); wait != 0 {
+		t.Errorf("Wait was incorrect, got: %s, expected: 0s", wait)
+	}
+}
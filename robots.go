@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type (
+	// robotsRules are the rules of a robots.txt that apply to every user agent
+	robotsRules []robotsRule
+
+	robotsRule struct {
+		path  string
+		allow bool
+	}
+
+	// robotsEntry holds the rules of a single origin. Its own lock makes
+	// requests wait only for the robots.txt of their origin.
+	robotsEntry struct {
+		mu     sync.Mutex
+		loaded bool
+		rules  robotsRules
+	}
+)
+
+// robotsDisallowAll are the rules of an origin whose robots.txt can't be
+// reached
+var robotsDisallowAll = robotsRules{{path: "/", allow: false}}
+
+// allowedByRobots reports whether the robots.txt of the host allows the given
+// URL to be fetched. Rules are fetched once per origin. An origin without a
+// robots.txt allows everything, while one whose robots.txt can't be reached
+// disallows everything until a later request reaches it (RFC 9309). A done
+// context returns its error instead, the robots.txt wasn't given a chance.
+func (f *HTTPFetcher) allowedByRobots(ctx context.Context, u *url.URL) (bool, error) {
+	if u.Path == "/robots.txt" {
+		return true, nil
+	}
+
+	origin := u.Scheme + "://" + u.Host
+
+	f.robotsMu.Lock()
+	if f.robots == nil {
+		f.robots = make(map[string]*robotsEntry)
+	}
+	entry, ok := f.robots[origin]
+	if !ok {
+		entry = &robotsEntry{}
+		f.robots[origin] = entry
+	}
+	f.robotsMu.Unlock()
+
+	entry.mu.Lock()
+	rules := entry.rules
+	if !entry.loaded {
+		var cacheable bool
+		rules, cacheable = f.fetchRobots(ctx, origin)
+		if ctx.Err() != nil {
+			entry.mu.Unlock()
+			return false, requestError(u.String(), ctx.Err())
+		}
+		if cacheable {
+			entry.rules = rules
+			entry.loaded = true
+		}
+	}
+	entry.mu.Unlock()
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return rules.allowed(path), nil
+}
+
+// fetchRobots returns the rules of the given origin and whether they can be
+// kept for later requests. Server and network errors are transient, so they
+// disallow everything without being kept. Callers check the context, whose
+// errors aren't the origin's fault.
+func (f *HTTPFetcher) fetchRobots(ctx context.Context, origin string) (robotsRules, bool) {
+	req, err := http.NewRequest("GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, true
+	}
+	req.Header.Set("Accept", "text/plain")
+
+	response, _, err := f.do(req.WithContext(ctx))
+	if err == nil {
+		return parseRobots(response.Body), true
+	}
+	if ctx.Err() != nil {
+		return nil, false
+	}
+
+	var perr *ProviderError
+	if errors.As(err, &perr) && perr.StatusCode >= 400 && perr.StatusCode < 500 && perr.StatusCode != http.StatusTooManyRequests {
+		DefaultLogger.Debug("robots.txt unavailable, allowing everything", "origin", origin, "status", perr.StatusCode)
+		return nil, true
+	}
+
+	DefaultLogger.Debug("robots.txt unreachable, disallowing everything", "origin", origin, "error", err)
+	return robotsDisallowAll, false
+}
+
+// parseRobots returns the rules of the groups for every user agent (*). We
+// rotate user agents, so rules for specific crawlers don't apply.
+func parseRobots(body []byte) robotsRules {
+	var rules robotsRules
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	inGroup := false
+	lastWasAgent := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		switch key {
+		case "user-agent":
+			// Consecutive user-agent lines share the same group
+			if !lastWasAgent {
+				inGroup = false
+			}
+			if value == "*" {
+				inGroup = true
+			}
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			if inGroup && value != "" {
+				rules = append(rules, robotsRule{path: value, allow: key == "allow"})
+			}
+		}
+		lastWasAgent = false
+	}
+
+	return rules
+}
+
+// allowed applies the most specific rule matching the path. Allow wins when
+// an allow and a disallow rule are equally specific.
+func (rules robotsRules) allowed(path string) bool {
+	allowed := true
+	longest := -1
+	for _, rule := range rules {
+		if !robotsMatch(rule.path, path) {
+			continue
+		}
+		if len(rule.path) > longest || (len(rule.path) == longest && rule.allow) {
+			allowed = rule.allow
+			longest = len(rule.path)
+		}
+	}
+	return allowed
+}
+
+// robotsMatch reports whether the path matches the pattern of a rule, which
+// may contain * wildcards and end with $ to match the end of the path
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+
+	if anchored && rest != "" {
+		// The last part may appear again further in the path
+		last := parts[len(parts)-1]
+		return len(parts) > 1 && strings.HasSuffix(path, last)
+	}
+	return true
+}
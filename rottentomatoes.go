@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -22,9 +24,18 @@ const scoreClassRotten = "rotten"
 const scoreClassFresh = "fresh"
 const scoreClassCertifiedFresh = "certified_fresh"
 
+// pageClassCertifiedFresh is the class of the Certified Fresh icon in pages,
+// unlike rotten public api which uses scoreClassCertifiedFresh
+const pageClassCertifiedFresh = "certified-fresh"
+
+const audienceClassUpright = "upright"
+const audienceClassSpilled = "spilled"
+
 type (
 	// RottenTomatoes represents an IMDB provider
-	RottenTomatoes struct{}
+	RottenTomatoes struct {
+		fetcher Fetcher
+	}
 
 	/* Response struct for url:
 	   https://www.rottentomatoes.com/napi/search?query="something"
@@ -43,10 +54,14 @@ type (
 	}
 
 	rtScoreResult struct {
-		Name       string
-		MeterClass string
-		MeterScore uint
-		Path       string
+		Name          string
+		MeterClass    string
+		MeterScore    uint
+		ReviewCount   uint
+		AudienceClass string
+		AudienceScore uint
+		RatingCount   uint
+		Path          string
 	}
 
 	rtActor struct {
@@ -103,95 +118,187 @@ type (
 	}
 )
 
-// NewRottenTomatoes creates a new instance of RottenTomatoes provider
-func NewRottenTomatoes() *RottenTomatoes {
-	return &RottenTomatoes{}
+// NewRottenTomatoes creates a new instance of RottenTomatoes provider that uses
+// the given fetcher to retrieve pages. A nil fetcher means DefaultFetcher.
+func NewRottenTomatoes(fetcher Fetcher) *RottenTomatoes {
+	if fetcher == nil {
+		fetcher = DefaultFetcher
+	}
+	return &RottenTomatoes{fetcher: fetcher}
 }
 
-// Search for movie, actors, shows, franchises, etc, using rotten public api.
-func (rt *RottenTomatoes) Search(query string) ([]SearchResult, error) {
+// Search for movies and shows using rotten public api. Returns the first
+// page of results.
+func (rt *RottenTomatoes) Search(ctx context.Context, query string) ([]SearchResult, error) {
 	if query == "" {
 		return nil, nil
 	}
 
-	query = url.QueryEscape(query)
-	url := rottenAPIBaseURL + "search/?limit=5&query=" + query
-
-	body, err := Get(url)
+	page, err := rt.SearchPage(ctx, query, defaultPage())
 	if err != nil {
 		return nil, err
 	}
+	return page.Results, nil
+}
 
-	var result rtSearchResult
-	err = json.Unmarshal(body, &result)
+// SearchPage returns the given page of movies and shows for a query. Rotten
+// public api doesn't page, so the same window of results is fetched and ranked
+// for every page.
+func (rt *RottenTomatoes) SearchPage(ctx context.Context, query string, page PageRequest) (*SearchPage, error) {
+	if query == "" {
+		return nil, errors.New("query is empty")
+	}
+
+	result, response, err := rt.search(ctx, query, rankWindow)
 	if err != nil {
 		return nil, err
 	}
 
+	meta := response.Meta()
 	r := make([]SearchResult, 0)
 	for _, movie := range result.Movies {
 		r = append(r, SearchResult{
-			ID:         movie.URL,
-			Title:      movie.Name,
-			Poster:     movie.Image,
-			Provider:   RottenT,
-			Score:      float32(movie.MeterScore),
-			ScoreClass: movie.MeterClass,
-			Year:       movie.Year,
+			ID:              movie.URL,
+			Title:           movie.Name,
+			Poster:          movie.Image,
+			Provider:        RottenT,
+			Score:           float32(movie.MeterScore),
+			NormalizedScore: normalizeScore(RottenT, float32(movie.MeterScore)),
+			ScoreClass:      movie.MeterClass,
+			Year:            movie.Year,
+			Kind:            KindMovie,
+			Meta:            meta,
+		})
+	}
+	for _, show := range result.TvSeries {
+		r = append(r, SearchResult{
+			ID:              show.URL,
+			Title:           show.Title,
+			Poster:          show.Image,
+			Provider:        RottenT,
+			Score:           float32(show.MeterScore),
+			NormalizedScore: normalizeScore(RottenT, float32(show.MeterScore)),
+			ScoreClass:      show.MeterClass,
+			Year:            show.StartYear,
+			EndYear:         show.EndYear,
+			Kind:            KindTV,
+			Meta:            meta,
 		})
 	}
 
-	return r, nil
+	rankResults(r, query, page.Year)
+
+	var total uint
+	switch page.Kind {
+	case KindMovie:
+		total = result.MovieCount
+	case KindTV:
+		total = result.TvCount
+	default:
+		total = result.MovieCount + result.TvCount
+	}
+
+	return paginate(r, page, total), nil
+}
+
+// search returns every kind of result of rotten public api for the given
+// query, up to limit results of each kind
+func (rt *RottenTomatoes) search(ctx context.Context, query string, limit uint) (*rtSearchResult, *Response, error) {
+	fullURL := fmt.Sprintf("%ssearch/?limit=%d&query=%s", rottenAPIBaseURL, limit, url.QueryEscape(query))
+
+	response, err := rt.fetcher.Get(ctx, fullURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result rtSearchResult
+	err = json.Unmarshal(response.Body, &result)
+	if err != nil {
+		return nil, nil, parseError(fullURL, err)
+	}
+
+	return &result, response, nil
 }
 
 // Score gets the score for the given rotten page path as id
-func (rt *RottenTomatoes) Score(id string) (*ScoreResult, error) {
+func (rt *RottenTomatoes) Score(ctx context.Context, id string) (*ScoreResult, error) {
 	if id == "" {
 		return nil, nil
 	}
 
-	path := id
-	finalPath := ensurePathHasM(path)
+	finalPath := rottenPath(id)
 	fullURL := rottenBaseURL + finalPath
-	body, err := Get(fullURL)
+	response, err := rt.fetcher.Get(ctx, fullURL)
 	if err != nil {
 		return nil, err
 	}
 
 	result := &rtScoreResult{}
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response.Body))
 	if err != nil {
-		return nil, err
+		return nil, parseError(fullURL, err)
 	}
 
-	container := doc.Find("#all-critics-numbers > div > div:nth-child(1) > div > div.critic-score.meter")
-
-	result.Name = strings.TrimSpace(doc.Find("#heroImageContainer > a > h1").Text())
+	result.Name = DefaultSelectors.Extract(doc, RottenT, "name")
 	result.Path = finalPath
-
-	meterScoreText := container.Find("span.meter-value.superPageFontColor > span").Text()
-	result.MeterScore = scoreAsInt(meterScoreText)
-
-	icon := container.Find("span.meter-tomato.icon")
-	val, exists := icon.Attr("class")
-	if exists {
-		if strings.Contains(val, scoreClassRotten) {
-			result.MeterClass = scoreClassRotten
-		} else if strings.Contains(val, scoreClassFresh) {
-			result.MeterClass = scoreClassFresh
-		} else if strings.Contains(val, scoreClassCertifiedFresh) {
-			result.MeterClass = scoreClassCertifiedFresh
-		}
+	result.MeterScore, err = parseScore(DefaultSelectors.Extract(doc, RottenT, "score"))
+	if err != nil {
+		return nil, parseError(fullURL, fmt.Errorf("couldn't find score for movie %s: %s", finalPath, err.Error()))
 	}
+	result.MeterClass = rottenScoreClass(DefaultSelectors.Extract(doc, RottenT, "score_class"))
+	result.ReviewCount = scoreAsInt(DefaultSelectors.Extract(doc, RottenT, "critic_count"))
+	result.AudienceScore = scoreAsInt(DefaultSelectors.Extract(doc, RottenT, "audience_score"))
+	result.AudienceClass = DefaultSelectors.Extract(doc, RottenT, "audience_class")
+	result.RatingCount = scoreAsInt(DefaultSelectors.Extract(doc, RottenT, "audience_count"))
 
 	return &ScoreResult{
-		Provider:   RottenT,
-		ID:         result.Path,
-		Score:      float32(result.MeterScore),
-		ScoreClass: result.MeterClass,
+		Provider:        RottenT,
+		ID:              result.Path,
+		Kind:            rottenKind(result.Path),
+		Score:           float32(result.MeterScore),
+		NormalizedScore: normalizeScore(RottenT, float32(result.MeterScore)),
+		ScoreClass:      result.MeterClass,
+		CriticCount:     result.ReviewCount,
+		AudienceScore:   float32(result.AudienceScore),
+		AudienceClass:   result.AudienceClass,
+		AudienceCount:   result.RatingCount,
+		Meta:            response.Meta(),
 	}, nil
 }
 
+// Details gets the metadata of the given movie from its page
+func (rt *RottenTomatoes) Details(ctx context.Context, id string) (*DetailsResult, error) {
+	if id == "" {
+		return nil, errors.New("id is empty")
+	}
+
+	finalPath := rottenPath(id)
+	fullURL := rottenBaseURL + finalPath
+	response, err := rt.fetcher.Get(ctx, fullURL)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(response.Body))
+	if err != nil {
+		return nil, parseError(fullURL, err)
+	}
+
+	result := &DetailsResult{Provider: RottenT, ID: finalPath}
+	if movie := findLinkedMovie(doc); movie != nil {
+		result = movie.details(RottenT, finalPath)
+	}
+	result.complete(doc, RottenT)
+	result.Kind = rottenKind(finalPath)
+
+	if result.Title == "" {
+		return nil, parseError(fullURL, fmt.Errorf("couldn't find details for movie %s", finalPath))
+	}
+
+	result.Meta = response.Meta()
+	return result, nil
+}
+
 func (rt *rtSearchResult) toOutputFormat() []rtSearchOutputFormat {
 	result := make([]rtSearchOutputFormat, 0)
 
@@ -215,6 +322,35 @@ func (rt *rtSearchResult) toOutputFormat() []rtSearchOutputFormat {
 	return result
 }
 
+// rottenPath returns the page path of the given id. TV series and seasons are
+// under /tv/, e.g. /tv/game_of_thrones/s08, anything else is a movie.
+func rottenPath(id string) string {
+	if strings.HasPrefix(id, "/tv/") {
+		return id
+	}
+	if strings.HasPrefix(id, "tv/") {
+		return "/" + id
+	}
+	return ensurePathHasM(id)
+}
+
+// rottenScoreClass returns the score class of the given class of a page icon,
+// so classes are the same in pages and rotten public api
+func rottenScoreClass(class string) string {
+	if class == pageClassCertifiedFresh {
+		return scoreClassCertifiedFresh
+	}
+	return class
+}
+
+// rottenKind returns the kind of title of the given page path
+func rottenKind(path string) string {
+	if strings.HasPrefix(path, "/tv/") {
+		return KindTV
+	}
+	return KindMovie
+}
+
 func ensurePathHasM(path string) string {
 	if strings.HasPrefix(path, "/m/") {
 		return path
@@ -230,20 +366,29 @@ func ensurePathHasM(path string) string {
 	return path
 }
 
+// scoreAsInt returns the number in the given text or zero when it has none.
+// Use parseScore to tell a missing number apart from a zero.
 func scoreAsInt(scoreText string) uint {
-	var result uint
+	result, _ := parseScore(scoreText)
+	return result
+}
+
+// parseScore returns the number in the given text ignoring anything around it
+// and thousands separators, e.g. 41316 for "User Ratings: 41,316"
+func parseScore(scoreText string) (uint, error) {
 	trimmed := strings.TrimFunc(scoreText, func(r rune) bool {
 		return !unicode.IsNumber(r)
 	})
+	// Counts use thousands separators, e.g. 41,316
+	trimmed = strings.Replace(trimmed, ",", "", -1)
 
-	if trimmed != "" {
-		number, err := strconv.Atoi(trimmed)
-		if err != nil {
-			fmt.Printf("Error: %s\n", err.Error())
-		}
-
-		result = uint(number)
+	if trimmed == "" {
+		return 0, fmt.Errorf("couldn't find a number in '%s'", strings.TrimSpace(scoreText))
 	}
 
-	return result
+	number, err := strconv.ParseUint(trimmed, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(number), nil
 }
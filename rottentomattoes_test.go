@@ -1,12 +1,15 @@
 package main
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestRottenSearch(t *testing.T) {
-	rotten := NewRottenTomatoes()
+	rotten := NewRottenTomatoes(testFetcher)
 
 	query := "iron man"
-	result, err := rotten.Search(query)
+	result, err := rotten.Search(context.Background(), query)
 	if err != nil {
 		t.Error(err)
 	}
@@ -35,13 +38,12 @@ func TestRottenSearch(t *testing.T) {
 }
 
 func TestRottenScore(t *testing.T) {
-	rotten := NewRottenTomatoes()
+	rotten := NewRottenTomatoes(testFetcher)
 
-	// NOTE: this can break if movie score changes...
 	path := "/m/sharknado_2013"
-	result, err := rotten.Score(path)
+	result, err := rotten.Score(context.Background(), path)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
 	expectedScore := float32(82)
@@ -56,6 +58,44 @@ func TestRottenScore(t *testing.T) {
 	}
 }
 
+func TestRottenAudienceScore(t *testing.T) {
+	rotten := NewRottenTomatoes(testFetcher)
+
+	result, err := rotten.Score(context.Background(), "/m/sharknado_2013")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.CriticCount != 28 {
+		t.Errorf("Critic count was incorrect, got %d, expected: %d", result.CriticCount, 28)
+	}
+	if result.AudienceScore != 33 {
+		t.Errorf("Audience score was incorrect, got %f, expected: %f", result.AudienceScore, float32(33))
+	}
+	if result.AudienceClass != audienceClassSpilled {
+		t.Errorf("Audience class was incorrect, got %s, expected: %s", result.AudienceClass, audienceClassSpilled)
+	}
+	if result.AudienceCount != 41316 {
+		t.Errorf("Audience count was incorrect, got %d, expected: %d", result.AudienceCount, 41316)
+	}
+}
+
+func TestRottenCertifiedFresh(t *testing.T) {
+	rotten := NewRottenTomatoes(testFetcher)
+
+	result, err := rotten.Score(context.Background(), "/m/iron_man")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ScoreClass != scoreClassCertifiedFresh {
+		t.Errorf("Score class was incorrect, got %s, expected: %s", result.ScoreClass, scoreClassCertifiedFresh)
+	}
+	if result.AudienceClass != audienceClassUpright {
+		t.Errorf("Audience class was incorrect, got %s, expected: %s", result.AudienceClass, audienceClassUpright)
+	}
+}
+
 func isMovieEqual(a SearchResult, b rtMovie) bool {
 	return (a.Title == b.Name &&
 		a.ID == b.URL &&
@@ -65,3 +105,44 @@ func isMovieEqual(a SearchResult, b rtMovie) bool {
 func isScoreClassOneOf(scoreClass string) bool {
 	return scoreClass == "rotten" || scoreClass == "fresh" || scoreClass == "certified_fresh"
 }
+
+func TestRottenSearchType(t *testing.T) {
+	result, err := NewRottenTomatoes(testFetcher).Search(context.Background(), "iron man")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shows := filterKind(result, KindTV)
+	if len(shows) != 1 {
+		t.Fatalf("TV series count was incorrect, got: %d, expected: 1", len(shows))
+	}
+	if shows[0].ID != "/tv/iron_man_armored_adventures" || shows[0].Year != 2009 || shows[0].EndYear != 2012 {
+		t.Errorf("TV series was incorrect, got: %+v", shows[0])
+	}
+
+	for _, movie := range filterKind(result, KindMovie) {
+		if movie.Kind != KindMovie {
+			t.Errorf("Kind was incorrect, got: %s, expected: %s", movie.Kind, KindMovie)
+		}
+	}
+	if len(filterKind(result, KindAny)) != len(result) {
+		t.Errorf("Any kind filtered out results")
+	}
+}
+
+func TestRottenScoreSeason(t *testing.T) {
+	result, err := NewRottenTomatoes(testFetcher).Score(context.Background(), "tv/game_of_thrones/s08")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ID != "/tv/game_of_thrones/s08" || result.Kind != KindTV {
+		t.Errorf("Result was incorrect, got: %s of kind %s", result.ID, result.Kind)
+	}
+	if result.Score != 58 || result.ScoreClass != scoreClassRotten {
+		t.Errorf("Score was incorrect, got: %f %s, expected: 58 rotten", result.Score, result.ScoreClass)
+	}
+	if result.AudienceScore != 30 || result.AudienceCount != 56214 {
+		t.Errorf("Audience score was incorrect, got: %f with %d ratings", result.AudienceScore, result.AudienceCount)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+// DefaultSearchLimit is the number of results of a search page when no limit
+// is given
+const DefaultSearchLimit = 10
+
+// MaxSearchLimit is the largest number of results of a search page
+const MaxSearchLimit = 100
+
+// rankWindow is the number of results fetched from providers that don't page.
+// Results are ranked within the window, so it must not depend on the page or
+// a title could show up in two pages. Pages past the window are empty.
+const rankWindow = MaxSearchLimit
+
+type (
+	// PageRequest represents which search results to return. Kind filters
+	// results before paging them. Year, when not zero, ranks the results
+	// released around it first.
+	PageRequest struct {
+		Kind   string
+		Year   uint
+		Offset uint
+		Limit  uint
+	}
+
+	// SearchPage represents the result for a search operation. Total is the
+	// number of results reported by the provider, zero when it doesn't.
+	SearchPage struct {
+		Total   uint           `json:"total,omitempty"`
+		Offset  uint           `json:"offset"`
+		Limit   uint           `json:"limit"`
+		Results []SearchResult `json:"results"`
+	}
+)
+
+// defaultPage returns the first page of results of any kind
+func defaultPage() PageRequest {
+	return PageRequest{Kind: KindAny, Limit: DefaultSearchLimit}
+}
+
+func (page PageRequest) limit() uint {
+	if page.Limit == 0 {
+		return DefaultSearchLimit
+	}
+	return page.Limit
+}
+
+// paginate filters the results by kind and returns the requested page of them
+func paginate(results []SearchResult, page PageRequest, total uint) *SearchPage {
+	results = filterKind(results, page.Kind)
+
+	start := page.Offset
+	if start > uint(len(results)) {
+		start = uint(len(results))
+	}
+	end := start + page.limit()
+	if end > uint(len(results)) {
+		end = uint(len(results))
+	}
+
+	return &SearchPage{
+		Total:   total,
+		Offset:  page.Offset,
+		Limit:   page.limit(),
+		Results: results[start:end],
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRottenSearchPage(t *testing.T) {
+	rotten := NewRottenTomatoes(testFetcher)
+
+	first, err := rotten.SearchPage(context.Background(), "iron man", PageRequest{Kind: KindMovie, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := rotten.SearchPage(context.Background(), "iron man", PageRequest{Kind: KindMovie, Offset: 2, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Total != 38 {
+		t.Errorf("Total was incorrect, got: %d, expected: %d", first.Total, 38)
+	}
+	if len(first.Results) != 2 || len(second.Results) != 2 {
+		t.Fatalf("Page sizes were incorrect, got: %d and %d, expected: 2", len(first.Results), len(second.Results))
+	}
+	if first.Results[1].ID == second.Results[0].ID {
+		t.Errorf("Pages overlap, both have %s", first.Results[1].ID)
+	}
+
+	tv, err := rotten.SearchPage(context.Background(), "iron man", PageRequest{Kind: KindTV})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tv.Total != 1 || len(tv.Results) != 1 {
+		t.Errorf("TV page was incorrect, got: %+v", tv)
+	}
+}
+
+func TestRottenSearchPagesRanked(t *testing.T) {
+	// More results than a page, in an order ranking changes
+	var search rtSearchResult
+	for i := 0; i < 25; i++ {
+		search.Movies = append(search.Movies, rtMovie{
+			Name: fmt.Sprintf("Halloween %s", strings.Repeat("Night ", i%5)),
+			URL:  fmt.Sprintf("/m/halloween_%d", i),
+			Year: uint(1978 + i),
+		})
+	}
+	search.MovieCount = uint(len(search.Movies))
+	body, err := json.Marshal(search)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotten := NewRottenTomatoes(stubFetcher{
+		fmt.Sprintf("%ssearch/?limit=%d&query=halloween", rottenAPIBaseURL, rankWindow): string(body),
+	})
+
+	seen := make(map[string]int)
+	for page := uint(1); page <= 3; page++ {
+		result, err := rotten.SearchPage(context.Background(), "halloween", PageRequest{Kind: KindMovie, Year: 2000, Offset: (page - 1) * 10, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range result.Results {
+			if previous, ok := seen[r.ID]; ok {
+				t.Errorf("%s is in pages %d and %d", r.ID, previous, page)
+			}
+			seen[r.ID] = int(page)
+		}
+	}
+
+	// Every result is in some page
+	if len(seen) != len(search.Movies) {
+		t.Errorf("Number of results in pages was incorrect, got: %d, expected: %d", len(seen), len(search.Movies))
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	results := make([]SearchResult, 5)
+	for i := range results {
+		results[i] = SearchResult{ID: string(rune('a' + i)), Kind: KindMovie}
+	}
+
+	cases := []struct {
+		page     PageRequest
+		expected int
+	}{
+		{PageRequest{Kind: KindAny}, 5},
+		{PageRequest{Kind: KindAny, Limit: 2}, 2},
+		{PageRequest{Kind: KindAny, Offset: 4, Limit: 2}, 1},
+		{PageRequest{Kind: KindAny, Offset: 10, Limit: 2}, 0},
+		{PageRequest{Kind: KindTV}, 0},
+	}
+
+	for _, c := range cases {
+		page := paginate(results, c.page, 0)
+		if len(page.Results) != c.expected {
+			t.Errorf("Size of page %+v was incorrect, got: %d, expected: %d", c.page, len(page.Results), c.expected)
+		}
+	}
+}
+
+func TestFilterKind(t *testing.T) {
+	results := []SearchResult{
+		{ID: "tt0371746", Kind: imdbKind("feature")},
+		{ID: "tt0837143", Kind: imdbKind("TV series")},
+		{ID: "nm0000375", Kind: imdbKind("")},
+		{ID: "tt1233205", Kind: imdbKind("video game")},
+	}
+
+	cases := map[string][]string{
+		KindMovie: {"tt0371746"},
+		KindTV:    {"tt0837143"},
+		KindAny:   {"tt0371746", "tt0837143"},
+	}
+
+	for kind, expected := range cases {
+		filtered := filterKind(results, kind)
+		ids := make([]string, len(filtered))
+		for i, r := range filtered {
+			ids[i] = r.ID
+		}
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("Results of kind %s were incorrect, got: %v, expected: %v", kind, ids, expected)
+		}
+	}
+}
+
+func TestSearchRequestPaged(t *testing.T) {
+	req := Request{Provider: RottenT, Operation: opSearch, Query: "iron man", Type: KindMovie, Limit: 2}
+	result, err := req.execute(context.Background(), testFetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results, ok := result.([]SearchResult); !ok || len(results) != 2 {
+		t.Errorf("Result was incorrect, got: %#v", result)
+	}
+
+	req.Paged = true
+	result, err = req.execute(context.Background(), testFetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page, ok := result.(*SearchPage); !ok || page.Limit != 2 || len(page.Results) != 2 {
+		t.Errorf("Result was incorrect, got: %#v", result)
+	}
+}
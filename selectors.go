@@ -0,0 +1,195 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Transforms applied to the value extracted by a selector rule
+const (
+	transformTrim   = "trim"
+	transformDigits = "digits"
+)
+
+// selectorsVersion is the version of selector files this build understands
+const selectorsVersion = 1
+
+type (
+	// Selectors represents the rules used to extract every field from the
+	// pages of each provider. Rules of a field are tried in order until one
+	// of them extracts a value.
+	Selectors struct {
+		Version   int                                  `json:"version"`
+		Providers map[string]map[string][]SelectorRule `json:"providers"`
+	}
+
+	// SelectorRule represents how to extract a single value from a page
+	SelectorRule struct {
+		// Selector is the CSS selector of the element holding the value
+		Selector string `json:"selector"`
+		// Attr reads the given attribute instead of the text of the element
+		Attr string `json:"attr,omitempty"`
+		// Label reads the text following the given label in the children of
+		// the element, e.g. "28" for <div><span>Reviews Counted:</span> 28</div>
+		Label string `json:"label,omitempty"`
+		// Contains turns the value into the first of the given strings it
+		// contains, e.g. a class among the classes of an icon
+		Contains []string `json:"contains,omitempty"`
+		// Transform is either trim (default) or digits
+		Transform string `json:"transform,omitempty"`
+	}
+)
+
+//go:embed selectors.json
+var defaultSelectorsJSON []byte
+
+// DefaultSelectors are the rules used by providers to parse pages. They are
+// embedded in the binary and can be replaced by -selectors.
+var DefaultSelectors = mustParseSelectors(defaultSelectorsJSON)
+
+// LoadSelectors reads the rules from the given JSON file
+func LoadSelectors(filename string) (*Selectors, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	selectors, err := parseSelectors(data)
+	if err != nil {
+		return nil, fmt.Errorf("selectors file '%s' is invalid: %s", filename, err.Error())
+	}
+	return selectors, nil
+}
+
+func mustParseSelectors(data []byte) *Selectors {
+	selectors, err := parseSelectors(data)
+	if err != nil {
+		panic(err)
+	}
+	return selectors
+}
+
+func parseSelectors(data []byte) (*Selectors, error) {
+	var selectors Selectors
+	if err := json.Unmarshal(data, &selectors); err != nil {
+		return nil, err
+	}
+
+	if selectors.Version != selectorsVersion {
+		return nil, fmt.Errorf("version %d is not supported, expected %d", selectors.Version, selectorsVersion)
+	}
+
+	for provider, fields := range selectors.Providers {
+		for field, rules := range fields {
+			for _, rule := range rules {
+				if rule.Selector == "" {
+					return nil, fmt.Errorf("rule of %s.%s has no selector", provider, field)
+				}
+				if rule.Transform != "" && rule.Transform != transformTrim && rule.Transform != transformDigits {
+					return nil, fmt.Errorf("transform '%s' of %s.%s is not supported", rule.Transform, provider, field)
+				}
+			}
+		}
+	}
+
+	if len(selectors.Providers) == 0 {
+		return nil, errors.New("no provider rules defined")
+	}
+
+	return &selectors, nil
+}
+
+// Extract returns the value of the given field of a provider page using the
+// first rule that finds one, or an empty string if none does
+func (s *Selectors) Extract(doc *goquery.Document, provider, field string) string {
+	return s.ExtractFrom(doc.Selection, provider, field)
+}
+
+// ExtractFrom is like Extract but only looks inside the given selection, e.g.
+// an item returned by Find
+func (s *Selectors) ExtractFrom(root *goquery.Selection, provider, field string) string {
+	for _, rule := range s.Providers[provider][field] {
+		if value := rule.extract(root); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Find returns every element matched by the first rule of the given field
+// that matches any, which is useful for lists of items
+func (s *Selectors) Find(doc *goquery.Document, provider, field string) *goquery.Selection {
+	for _, rule := range s.Providers[provider][field] {
+		if sel := doc.Find(rule.Selector); sel.Length() > 0 {
+			return sel
+		}
+	}
+	return doc.Find("")
+}
+
+func (rule *SelectorRule) extract(root *goquery.Selection) string {
+	sel := root.Find(rule.Selector).First()
+	if sel.Length() == 0 {
+		return ""
+	}
+
+	var value string
+	switch {
+	case rule.Label != "":
+		value = labeledValue(sel, rule.Label)
+	case rule.Attr != "":
+		value, _ = sel.Attr(rule.Attr)
+	default:
+		value = sel.Text()
+	}
+
+	if len(rule.Contains) > 0 {
+		value = firstContained(value, rule.Contains)
+	}
+
+	value = strings.TrimSpace(value)
+	if rule.Transform == transformDigits {
+		value = strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, value)
+	}
+
+	return value
+}
+
+// firstContained returns the first of the given strings contained in value,
+// or an empty string if none is
+func firstContained(value string, candidates []string) string {
+	for _, c := range candidates {
+		if strings.Contains(value, c) {
+			return c
+		}
+	}
+	return ""
+}
+
+// labeledValue returns the text following the given label in the children of
+// the selection, e.g. "28" for <div><span>Reviews Counted:</span> 28</div>
+func labeledValue(sel *goquery.Selection, label string) string {
+	value := ""
+	sel.Children().EachWithBreak(func(i int, child *goquery.Selection) bool {
+		text := strings.TrimSpace(child.Text())
+		if !strings.HasPrefix(text, label) {
+			return true
+		}
+
+		value = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(text, label), ":"))
+		return false
+	})
+	return value
+}
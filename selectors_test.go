@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestSelectorsFallback(t *testing.T) {
+	doc := testDocument(t, `<html><body>
+		<div class="new"><span class="icon tomato certified_fresh"></span><b>Reviews: 1,204</b></div>
+	</body></html>`)
+
+	selectors := mustParseSelectors([]byte(`{
+		"version": 1,
+		"providers": {
+			"rotten": {
+				"score_class": [
+					{"selector": "div.old span.icon", "attr": "class", "contains": ["rotten", "fresh"]},
+					{"selector": "div.new span.icon", "attr": "class", "contains": ["certified_fresh", "rotten", "fresh"]}
+				],
+				"critic_count": [
+					{"selector": "div.new b", "transform": "digits"}
+				]
+			}
+		}
+	}`))
+
+	if class := selectors.Extract(doc, RottenT, "score_class"); class != "certified_fresh" {
+		t.Errorf("Class was incorrect, got: %s, expected: certified_fresh", class)
+	}
+	if count := selectors.Extract(doc, RottenT, "critic_count"); count != "1204" {
+		t.Errorf("Count was incorrect, got: %s, expected: 1204", count)
+	}
+	if missing := selectors.Extract(doc, RottenT, "name"); missing != "" {
+		t.Errorf("Missing field was incorrect, got: %s, expected empty", missing)
+	}
+}
+
+func TestLoadSelectors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "selectors.json")
+	ioutil.WriteFile(filename, []byte(`{"version": 2, "providers": {}}`), 0644)
+	if _, err := LoadSelectors(filename); err == nil {
+		t.Errorf("Unsupported version was loaded")
+	}
+
+	ioutil.WriteFile(filename, defaultSelectorsJSON, 0644)
+	selectors, err := LoadSelectors(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A custom file replaces the embedded rules
+	defer func(s *Selectors) { DefaultSelectors = s }(DefaultSelectors)
+	selectors.Providers[RottenT]["audience_score"] = []SelectorRule{{Selector: "#missing"}}
+	DefaultSelectors = selectors
+
+	result, err := NewRottenTomatoes(testFetcher).Score(context.Background(), "/m/sharknado_2013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score != 82 || result.AudienceScore != 0 {
+		t.Errorf("Scores were incorrect, got: %f and %f, expected: 82 and 0", result.Score, result.AudienceScore)
+	}
+}
+
+func testDocument(t *testing.T, html string) *goquery.Document {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
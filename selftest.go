@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+type (
+	// SelftestCanary represents a well known title used to check whether a
+	// provider can still be parsed. Query is searched and must list ID.
+	SelftestCanary struct {
+		Provider string `json:"provider"`
+		ID       string `json:"id"`
+		Query    string `json:"query,omitempty"`
+	}
+
+	// SelftestReport represents the result for a selftest operation. OK is
+	// false when any check failed.
+	SelftestReport struct {
+		OK     bool            `json:"ok"`
+		Failed int             `json:"failed"`
+		Checks []SelftestCheck `json:"checks"`
+	}
+
+	// SelftestCheck represents the outcome of checking a single field of a
+	// canary title
+	SelftestCheck struct {
+		Provider  string      `json:"provider"`
+		Operation string      `json:"op"`
+		ID        string      `json:"id"`
+		Field     string      `json:"field"`
+		OK        bool        `json:"ok"`
+		Value     interface{} `json:"value,omitempty"`
+		Error     string      `json:"error,omitempty"`
+	}
+
+	// fieldCheck returns the value of a field of a score result and whether
+	// it looks sane
+	fieldCheck struct {
+		field string
+		check func(r *ScoreResult) (interface{}, bool)
+	}
+)
+
+// SelftestCanaries are the titles checked by the selftest operation. They are
+// old enough for their scores to be stable and are part of testdata, so the
+// selftest also runs offline with -fixtures testdata.
+var SelftestCanaries = []SelftestCanary{
+	{Provider: IMDB, ID: "tt0371746", Query: "iron man"},
+	{Provider: IMDB, ID: "tt1228705"},
+	{Provider: RottenT, ID: "/m/iron_man", Query: "iron man"},
+	{Provider: RottenT, ID: "/m/sharknado_2013"},
+}
+
+// scoreChecks are the fields every canary score result of a provider must
+// have, along with their sanity checks
+var scoreChecks = map[string][]fieldCheck{
+	IMDB: {
+		{"score", func(r *ScoreResult) (interface{}, bool) { return r.Score, r.Score >= 1 && r.Score <= 10 }},
+		{"votes", func(r *ScoreResult) (interface{}, bool) { return r.Votes, r.Votes > 0 }},
+		{"metascore", func(r *ScoreResult) (interface{}, bool) { return r.Metascore, r.Metascore > 0 && r.Metascore <= 100 }},
+	},
+	RottenT: {
+		{"score", func(r *ScoreResult) (interface{}, bool) { return r.Score, r.Score > 0 && r.Score <= 100 }},
+		{"score_class", func(r *ScoreResult) (interface{}, bool) {
+			return r.ScoreClass, isArgValid(r.ScoreClass, []string{scoreClassRotten, scoreClassFresh, scoreClassCertifiedFresh})
+		}},
+		{"critic_count", func(r *ScoreResult) (interface{}, bool) { return r.CriticCount, r.CriticCount > 0 }},
+		{"audience_score", func(r *ScoreResult) (interface{}, bool) {
+			return r.AudienceScore, r.AudienceScore > 0 && r.AudienceScore <= 100
+		}},
+		{"audience_class", func(r *ScoreResult) (interface{}, bool) {
+			return r.AudienceClass, isArgValid(r.AudienceClass, []string{audienceClassUpright, audienceClassSpilled})
+		}},
+		{"audience_count", func(r *ScoreResult) (interface{}, bool) { return r.AudienceCount, r.AudienceCount > 0 }},
+	},
+}
+
+// Selftest scores and searches every canary of the given providers and checks
+// that each expected field is extracted with a sane value
+func Selftest(ctx context.Context, fetcher Fetcher, canaries []SelftestCanary) *SelftestReport {
+	report := &SelftestReport{Checks: make([]SelftestCheck, 0)}
+
+	// Canaries check the live pages, a cached page would hide a broken layout
+	if cache, ok := fetcher.(*CacheFetcher); ok {
+		fetcher = cache.Upstream
+	}
+
+	for _, canary := range canaries {
+		p := newProvider(canary.Provider, fetcher)
+		if p == nil {
+			continue
+		}
+
+		report.Checks = append(report.Checks, checkScore(ctx, p, canary)...)
+		if canary.Query != "" {
+			report.Checks = append(report.Checks, checkSearch(ctx, p, canary))
+		}
+	}
+
+	for _, check := range report.Checks {
+		if !check.OK {
+			report.Failed++
+		}
+	}
+	report.OK = report.Failed == 0
+
+	return report
+}
+
+func checkScore(ctx context.Context, p Provider, canary SelftestCanary) []SelftestCheck {
+	checks := scoreChecks[canary.Provider]
+	result := make([]SelftestCheck, 0, len(checks))
+
+	score, err := p.Score(ctx, canary.ID)
+	if err == nil && score == nil {
+		err = fmt.Errorf("no score found for %s", canary.ID)
+	}
+
+	for _, c := range checks {
+		check := SelftestCheck{
+			Provider:  canary.Provider,
+			Operation: opScore,
+			ID:        canary.ID,
+			Field:     c.field,
+		}
+
+		if err != nil {
+			check.Error = err.Error()
+		} else {
+			check.Value, check.OK = c.check(score)
+			if !check.OK {
+				check.Error = "value is missing or out of range"
+			}
+		}
+
+		result = append(result, check)
+	}
+
+	return result
+}
+
+func checkSearch(ctx context.Context, p Provider, canary SelftestCanary) SelftestCheck {
+	check := SelftestCheck{
+		Provider:  canary.Provider,
+		Operation: opSearch,
+		ID:        canary.ID,
+		Field:     "results",
+	}
+
+	results, err := p.Search(ctx, canary.Query)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	check.Value = len(results)
+	for _, r := range results {
+		if r.ID == canary.ID {
+			check.OK = true
+			return check
+		}
+	}
+
+	check.Error = fmt.Sprintf("%s isn't listed in results for '%s'", canary.ID, canary.Query)
+	return check
+}
+
+// selftestCanaries returns the canaries of the given provider, or every
+// canary when provider is all
+func selftestCanaries(provider string) []SelftestCanary {
+	if provider == AllProviders {
+		return SelftestCanaries
+	}
+
+	result := make([]SelftestCanary, 0)
+	for _, canary := range SelftestCanaries {
+		if canary.Provider == provider {
+			result = append(result, canary)
+		}
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSelftest(t *testing.T) {
+	report := Selftest(context.Background(), testFetcher, SelftestCanaries)
+	if !report.OK {
+		for _, check := range report.Checks {
+			if !check.OK {
+				t.Errorf("Check %s %s %s failed: %s", check.Provider, check.ID, check.Field, check.Error)
+			}
+		}
+	}
+
+	if len(report.Checks) == 0 {
+		t.Errorf("Report has no checks")
+	}
+}
+
+func TestSelftestBrokenLayout(t *testing.T) {
+	fetcher := stubFetcher{
+		rottenBaseURL + "/m/iron_man": `<html><body>
+			<div id="all-critics-numbers"><div><div><div><div class="critic-score meter">
+				<span class="meter-value superPageFontColor"><span>94</span></span>
+			</div></div></div></div></div>
+		</body></html>`,
+	}
+
+	report := Selftest(context.Background(), fetcher, []SelftestCanary{{Provider: RottenT, ID: "/m/iron_man"}})
+	if report.OK {
+		t.Fatalf("Report was OK for a page without audience score")
+	}
+
+	failed := make(map[string]bool)
+	for _, check := range report.Checks {
+		if !check.OK {
+			failed[check.Field] = true
+		}
+	}
+
+	if failed["score"] {
+		t.Errorf("Score check failed, expected it to pass")
+	}
+	for _, field := range []string{"score_class", "audience_score", "audience_count"} {
+		if !failed[field] {
+			t.Errorf("Check %s passed, expected it to fail", field)
+		}
+	}
+	if report.Failed != len(failed) {
+		t.Errorf("Failed count was incorrect, got: %d, expected: %d", report.Failed, len(failed))
+	}
+}
+
+func TestSelftestSkipsCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The cache still has the page from before the layout broke
+	if _, err := NewRottenTomatoes(NewCacheFetcher(dir, testFetcher)).Score(context.Background(), "/m/iron_man"); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := NewCacheFetcher(dir, stubFetcher{
+		rottenBaseURL + "/m/iron_man": `<html><body></body></html>`,
+	})
+	report := Selftest(context.Background(), broken, []SelftestCanary{{Provider: RottenT, ID: "/m/iron_man"}})
+	if report.OK {
+		t.Errorf("Report was OK for a broken live page")
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type (
+	// Server exposes the operations of every provider as a JSON API
+	Server struct {
+		fetcher Fetcher
+		// Timeout limits how long each request may take, zero means no limit
+		Timeout time.Duration
+	}
+)
+
+// NewServer creates a new instance of Server that uses the given fetcher to
+// retrieve pages. A nil fetcher means DefaultFetcher.
+func NewServer(fetcher Fetcher) *Server {
+	if fetcher == nil {
+		fetcher = DefaultFetcher
+	}
+	return &Server{fetcher: fetcher}
+}
+
+// Handler returns the handler with all API routes:
+//
+//	GET /search?provider=imdb&q=iron+man
+//	GET /search?provider=rotten&q=iron+man&type=tv
+//	GET /search?provider=rotten&q=halloween&limit=20&page=2&paged=true
+//	GET /search?provider=imdb&q=the+lion+king&year=1994
+//	GET /score?provider=rotten&id=/m/iron_man
+//	GET /score?provider=all&id=imdb=tt0371746,rotten=/m/iron_man
+//	GET /details?provider=imdb&id=tt0371746
+//	GET /match?q=iron+man&year=2008
+//	GET /aggregate?id=imdb=tt0371746,rotten=/m/iron_man&weights=imdb=2
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", s.handleSearch)
+	mux.HandleFunc("/score", s.handleScore)
+	mux.HandleFunc("/details", s.handleDetails)
+	mux.HandleFunc("/match", s.handleMatch)
+	mux.HandleFunc("/aggregate", s.handleAggregate)
+	return mux
+}
+
+// ListenAndServe listens on the given address and serves the API
+func (s *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, s.Handler())
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "query is required for search operation")
+		return
+	}
+
+	kind := r.URL.Query().Get("type")
+	if kind == "" {
+		kind = KindMovie
+	}
+	if !isArgValid(kind, supportedKinds) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("type '%s' is not supported", kind))
+		return
+	}
+
+	page, err := pageParams(r, kind)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Only paged searches get the total, offset and limit along with results
+	var paged bool
+	if value := r.URL.Query().Get("paged"); value != "" {
+		if paged, err = strconv.ParseBool(value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("paged '%s' is invalid", value))
+			return
+		}
+	}
+
+	p := s.provider(w, r)
+	if p == nil {
+		return
+	}
+
+	ctx, cancel := withTimeout(r.Context(), s.Timeout)
+	defer cancel()
+
+	result, err := p.SearchPage(ctx, query, page)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+
+	if paged {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result.Results)
+}
+
+func (s *Server) handleScore(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "id is required for score operation")
+		return
+	}
+
+	var ratings bool
+	if value := r.URL.Query().Get("ratings"); value != "" {
+		var err error
+		if ratings, err = strconv.ParseBool(value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("ratings '%s' is invalid", value))
+			return
+		}
+	}
+
+	ctx, cancel := withTimeout(r.Context(), s.Timeout)
+	defer cancel()
+
+	if r.URL.Query().Get("provider") == AllProviders {
+		ids, err := parseProviderIDs(id)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ScoreAll(ctx, ids, s.fetcher, ratings))
+		return
+	}
+
+	p := s.provider(w, r)
+	if p == nil {
+		return
+	}
+	p = withRatings(p, ratings)
+
+	result, err := score(ctx, p, id)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleDetails(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "id is required for details operation")
+		return
+	}
+
+	p := s.provider(w, r)
+	if p == nil {
+		return
+	}
+
+	ctx, cancel := withTimeout(r.Context(), s.Timeout)
+	defer cancel()
+
+	result, err := p.Details(ctx, id)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleMatch(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "query is required for match operation")
+		return
+	}
+
+	var year uint64
+	if value := r.URL.Query().Get("year"); value != "" {
+		var err error
+		year, err = strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("year '%s' is invalid", value))
+			return
+		}
+	}
+
+	ctx, cancel := withTimeout(r.Context(), s.Timeout)
+	defer cancel()
+
+	result, err := Match(ctx, query, uint(year), s.fetcher)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleAggregate(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	ids, err := parseProviderIDs(r.URL.Query().Get("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	weights, err := parseWeights(r.URL.Query().Get("weights"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := withTimeout(r.Context(), s.Timeout)
+	defer cancel()
+
+	result, err := Aggregate(ctx, ids, weights, s.fetcher)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// provider returns the provider requested in the query string or writes an
+// error response and returns nil if it isn't supported
+func (s *Server) provider(w http.ResponseWriter, r *http.Request) Provider {
+	name := r.URL.Query().Get("provider")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "provider is required")
+		return nil
+	}
+
+	if !isProviderSupported(name) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("provider '%s' is not supported", name))
+		return nil
+	}
+
+	return newProvider(name, s.fetcher)
+}
+
+// pageParams returns the page of search results selected by the limit and
+// either offset or page parameters of the request, ranked by its year
+func pageParams(r *http.Request, kind string) (PageRequest, error) {
+	page := PageRequest{Kind: kind}
+
+	params := map[string]*uint{"limit": &page.Limit, "offset": &page.Offset, "year": &page.Year}
+	var number uint
+	params["page"] = &number
+
+	for name, value := range params {
+		text := r.URL.Query().Get(name)
+		if text == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(text, 10, 32)
+		if err != nil {
+			return page, fmt.Errorf("%s must be a positive number", name)
+		}
+		*value = uint(n)
+	}
+
+	if page.Limit > MaxSearchLimit {
+		return page, fmt.Errorf("limit can't be greater than %d", MaxSearchLimit)
+	}
+	if number > 0 {
+		if page.Offset > 0 {
+			return page, errors.New("page and offset can't be used together")
+		}
+		page.Offset = (number - 1) * page.limit()
+	}
+
+	return page, nil
+}
+
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+	return true
+}
+
+// writeError writes an error caused by the request itself
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, Failure{
+		Error: &ErrorOutput{
+			Kind:    "invalid_request",
+			Message: message,
+		},
+	})
+}
+
+// writeFailure writes an error returned by a provider using the status code
+// of its kind
+func writeFailure(w http.ResponseWriter, err error) {
+	writeJSON(w, kindOf(err).httpStatus, Failure{Error: newErrorOutput(err)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerScore(t *testing.T) {
+	handler := NewServer(testFetcher).Handler()
+
+	req := httptest.NewRequest("GET", "/score?provider=rotten&id=/m/sharknado_2013", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status was incorrect, got: %d, expected: %d", rec.Code, http.StatusOK)
+	}
+
+	var result ScoreResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Provider != RottenT || result.ID != "/m/sharknado_2013" {
+		t.Errorf("Result was incorrect, got: %+v", result)
+	}
+}
+
+func TestServerSearch(t *testing.T) {
+	handler := NewServer(testFetcher).Handler()
+
+	req := httptest.NewRequest("GET", "/search?provider=imdb&q=iron+man+2008", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status was incorrect, got: %d, expected: %d", rec.Code, http.StatusOK)
+	}
+
+	// Searches output the list of results unless paged is set
+	var result []SearchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result) == 0 || result[0].ID != "tt0371746" {
+		t.Errorf("Result was incorrect, got: %+v", result)
+	}
+}
+
+func TestServerSearchPage(t *testing.T) {
+	handler := NewServer(testFetcher).Handler()
+
+	req := httptest.NewRequest("GET", "/search?provider=rotten&q=iron+man&limit=2&page=2&paged=true", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var result SearchPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Offset != 2 || result.Limit != 2 || len(result.Results) != 2 || result.Total != 38 {
+		t.Errorf("Page was incorrect, got: %+v", result)
+	}
+
+	req = httptest.NewRequest("GET", "/search?provider=rotten&q=iron+man&page=2&offset=1", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Status was incorrect, got: %d, expected: %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServerDetails(t *testing.T) {
+	handler := NewServer(testFetcher).Handler()
+
+	req := httptest.NewRequest("GET", "/details?provider=imdb&id=tt0371746", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status was incorrect, got: %d, expected: %d", rec.Code, http.StatusOK)
+	}
+
+	var result DetailsResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Provider != IMDB || result.ID != "tt0371746" || result.Title != "Iron Man" || result.Year != 2008 {
+		t.Errorf("Result was incorrect, got: %+v", result)
+	}
+
+	req = httptest.NewRequest("GET", "/details?provider=imdb&id=tt0000000", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Status was incorrect, got: %d, expected: %d", rec.Code, http.StatusNotFound)
+	}
+
+	var failure Failure
+	if err := json.Unmarshal(rec.Body.Bytes(), &failure); err != nil || failure.Error == nil || failure.Error.Kind != "not_found" {
+		t.Errorf("Failure was incorrect, got: %s", rec.Body.String())
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	handler := NewServer(testFetcher).Handler()
+
+	cases := map[string]int{
+		"/search?provider=imdb":                   http.StatusBadRequest,
+		"/search?provider=netflix&q=iron+man":     http.StatusBadRequest,
+		"/score?provider=rotten":                  http.StatusBadRequest,
+		"/score?provider=rotten&id=/m/not_stored": http.StatusNotFound,
+		"/details?provider=imdb":                  http.StatusBadRequest,
+		"/match?q=iron+man&year=two":              http.StatusBadRequest,
+	}
+
+	for url, expected := range cases {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+
+		if rec.Code != expected {
+			t.Errorf("Status for %s was incorrect, got: %d, expected: %d", url, rec.Code, expected)
+		}
+
+		var body Failure
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == nil {
+			t.Errorf("Body for %s was not a JSON error: %s", url, rec.Body.String())
+		}
+	}
+}
+
+func TestServerScoreRatings(t *testing.T) {
+	handler := NewServer(testFetcher).Handler()
+
+	req := httptest.NewRequest("GET", "/score?provider=imdb&id=tt0371746&ratings=true", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var result ScoreResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Distribution) != 10 {
+		t.Errorf("Distribution was incorrect, got: %+v", result.Distribution)
+	}
+
+	req = httptest.NewRequest("GET", "/score?provider=all&id=imdb=tt0371746&ratings=true", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var all MultiScoreResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &all); err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Results) != 1 || all.Results[0].Result == nil || len(all.Results[0].Result.Distribution) != 10 {
+		t.Errorf("Result was incorrect, got: %+v", all)
+	}
+
+	req = httptest.NewRequest("GET", "/score?provider=imdb&id=tt0371746&ratings=maybe", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Status was incorrect, got: %d, expected: %d", rec.Code, http.StatusBadRequest)
+	}
+}